@@ -0,0 +1,127 @@
+package process_settings
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A CallbackPanicError is passed to the error handler when a WhenUpdated or OnChange
+// callback panics. The panic is recovered, so the remaining callbacks still run and the
+// monitor keeps reloading the settings.
+type CallbackPanicError struct {
+	Callback string      // Which callback panicked, e.g. "WhenUpdated callback"
+	Value    interface{} // The value the callback panicked with
+	Stack    []byte      // The stack trace of the goroutine at the time of the panic
+}
+
+func (e *CallbackPanicError) Error() string {
+	return fmt.Sprintf("The %s panicked: %v", e.Callback, e.Value)
+}
+
+// runCallback calls a callback, recovering and reporting any panic. When a callback timeout
+// is set, the callback runs on its own goroutine and runCallback returns once it finishes or
+// the timeout is exceeded, in which case a warning is logged and the callback is left to
+// finish in the background. running is set while the callback runs on its own goroutine, and
+// a callback whose previous call is still running is skipped, so that a callback never runs
+// concurrently with itself. Callbacks are not called once the ProcessSettings is closed.
+func (ps *ProcessSettings) runCallback(name string, running *int32, fn func()) {
+	if ps.isClosed() {
+		return
+	}
+	if ps.callbackTimeout <= 0 {
+		ps.callRecoveringPanic(name, fn)
+		return
+	}
+
+	if !atomic.CompareAndSwapInt32(running, 0, 1) {
+		ps.logger.Warn("A process settings callback is still running from a previous reload, skipping it",
+			"file_path", ps.FilePath,
+			"version", ps.loadSnapshot().version,
+			"callback", name,
+		)
+		return
+	}
+
+	done := make(chan struct{})
+	ps.callbacksInFlight.start()
+	go func() {
+		defer ps.callbacksInFlight.finish()
+		defer atomic.StoreInt32(running, 0)
+		defer close(done)
+		ps.callRecoveringPanic(name, fn)
+	}()
+
+	timer := time.NewTimer(ps.callbackTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		ps.logger.Warn("A process settings callback did not finish in time, continuing with the next callback",
+			"file_path", ps.FilePath,
+			"version", ps.loadSnapshot().version,
+			"callback", name,
+			"timeout", ps.callbackTimeout,
+		)
+	}
+}
+
+func (ps *ProcessSettings) callRecoveringPanic(name string, fn func()) {
+	defer func() {
+		if value := recover(); value != nil {
+			err := &CallbackPanicError{Callback: name, Value: value, Stack: debug.Stack()}
+			ps.logger.Error("Recovered from a panic in a process settings callback",
+				"file_path", ps.FilePath,
+				"version", ps.loadSnapshot().version,
+				"error_kind", "callback_panic",
+				"error", err,
+			)
+			ps.observer.OnCallbackPanic(err)
+			ps.reportError(err)
+		}
+	}()
+	fn()
+}
+
+// A callbackTracker counts the callbacks that are running on their own goroutines, so that
+// Stop and Close can wait for them. Unlike with a sync.WaitGroup, a reload may start a
+// callback while Stop or Close is waiting.
+type callbackTracker struct {
+	mu       sync.Mutex
+	inFlight int
+	idle     chan struct{} // Closed once the callbacks in flight have finished
+}
+
+func (t *callbackTracker) start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.inFlight == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.inFlight++
+}
+
+func (t *callbackTracker) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight--
+	if t.inFlight == 0 {
+		close(t.idle)
+	}
+}
+
+// wait waits until the callbacks in flight have finished.
+func (t *callbackTracker) wait() {
+	t.mu.Lock()
+	busy := t.inFlight > 0
+	done := t.idle
+	t.mu.Unlock()
+
+	if busy {
+		<-done
+	}
+}
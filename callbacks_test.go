@@ -0,0 +1,118 @@
+package process_settings
+
+import (
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSettings_CallbackPanics(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+
+	reportedErrors := make(chan error, 10)
+	settings, err := NewProcessSettingsFromFile(filePath, nil, WithErrorHandler(func(err error) { reportedErrors <- err }))
+	assert.Nil(t, err)
+
+	var updates int32
+	settings.WhenUpdated(func() { panic("boom") }, false)
+	settings.OnChange([]string{"frontend", "log_level"}, func(old, new interface{}) { panic("bang") })
+	settings.WhenUpdated(func() { atomic.AddInt32(&updates, 1) }, false)
+
+	t.Run("Later callbacks still run and the panics are reported", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 2, "warn")
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&updates))
+
+		var panicErr *CallbackPanicError
+		assert.True(t, errors.As(<-reportedErrors, &panicErr))
+		assert.Equal(t, "The OnChange callback for 'frontend.log_level' panicked: bang", panicErr.Error())
+		assert.NotEmpty(t, panicErr.Stack)
+
+		assert.True(t, errors.As(<-reportedErrors, &panicErr))
+		assert.Equal(t, "WhenUpdated callback", panicErr.Callback)
+		assert.Equal(t, "boom", panicErr.Value)
+	})
+
+	t.Run("The monitor keeps reloading after a callback panicked", func(t *testing.T) {
+		startMonitor(t, settings)
+
+		replaceSettingsFile(t, filePath, 3, "debug")
+		assertEventuallyReloaded(t, settings, "debug")
+		replaceSettingsFile(t, filePath, 4, "error")
+		assertEventuallyReloaded(t, settings, "error")
+
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&updates) == 3 }, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestProcessSettings_CallbackTimeout(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+	settings, err := NewProcessSettingsFromFile(filePath, nil, WithCallbackTimeout(10*time.Millisecond))
+	assert.Nil(t, err)
+
+	// Every call of the slow callback waits for a value to be sent.
+	release := make(chan struct{})
+	var slowCalls, slowFinished, updates int32
+	settings.WhenUpdated(func() {
+		atomic.AddInt32(&slowCalls, 1)
+		<-release
+		atomic.AddInt32(&slowFinished, 1)
+	}, false)
+	settings.WhenUpdated(func() { atomic.AddInt32(&updates, 1) }, false)
+
+	t.Run("Later callbacks run without waiting for a callback that timed out", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 2, "warn")
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&updates))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&slowFinished))
+	})
+
+	t.Run("Skips a callback whose previous call is still running", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 3, "debug")
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&updates))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&slowCalls))
+	})
+
+	t.Run("Stop waits for the callback that timed out while reloads continue", func(t *testing.T) {
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			settings.Stop()
+		}()
+
+		for version := 4; version < 8; version++ {
+			writeSettingsFile(t, filePath, version, []string{"info", "warn"}[version%2])
+			_, err := settings.Reload()
+			assert.Nil(t, err)
+		}
+		release <- struct{}{}
+		<-stopped
+
+		assert.Equal(t, int32(6), atomic.LoadInt32(&updates))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&slowFinished))
+	})
+
+	t.Run("Close waits for the callback that timed out to finish", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 8, "debug")
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		time.AfterFunc(10*time.Millisecond, func() { release <- struct{}{} })
+		assert.Nil(t, settings.Close())
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&slowFinished))
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	process_settings "github.com/Invoca/process_settings.go"
+	"gopkg.in/yaml.v3"
+)
+
+// readSettingsFiles reads the settings files in the settings folder tree in the order the
+// Ruby tool combines them, as listed by process_settings.SettingsFileNames, so the combined
+// file matches what DirectorySource reads.
+// Each settings file is returned as the hash Ruby would combine: its filename first,
+// followed by its target and settings.
+func readSettingsFiles(settingsFolder string) ([]*rubyHash, error) {
+	fileNames, err := process_settings.SettingsFileNames(settingsFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	settingsFiles := make([]*rubyHash, 0, len(fileNames))
+	for _, fileName := range fileNames {
+		settingsFile, err := readSettingsFile(settingsFolder, fileName)
+		if err != nil {
+			return nil, err
+		}
+		settingsFiles = append(settingsFiles, settingsFile)
+	}
+	return settingsFiles, nil
+}
+
+func readSettingsFile(settingsFolder, fileName string) (*rubyHash, error) {
+	contents, err := os.ReadFile(filepath.Join(settingsFolder, filepath.FromSlash(fileName)))
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(contents, &document); err != nil {
+		return nil, fmt.Errorf("The settings file %s cannot be parsed: %w", fileName, err)
+	}
+	value, err := loadPsych(&document)
+	if err != nil {
+		return nil, fmt.Errorf("The settings file %s cannot be loaded: %w", fileName, err)
+	}
+
+	file, ok := value.(*rubyHash)
+	if !ok {
+		return nil, fmt.Errorf("The settings file %s must be a map with target and settings", fileName)
+	}
+	if err := validateSettingsFile(fileName, file); err != nil {
+		return nil, err
+	}
+
+	settingsFile := &rubyHash{}
+	settingsFile.set("filename", fileName)
+	for i, key := range file.keys {
+		settingsFile.set(key, file.values[i])
+	}
+	return settingsFile, nil
+}
+
+// validateSettingsFile checks that a settings file has a map of settings and only a target
+// besides, which must be a map or true.
+func validateSettingsFile(fileName string, file *rubyHash) error {
+	for _, key := range file.keys {
+		if key != "target" && key != "settings" {
+			return fmt.Errorf("The settings file %s has an unknown key %v; only target and settings are allowed", fileName, key)
+		}
+	}
+
+	settings, _ := file.get("settings")
+	if _, ok := settings.(*rubyHash); !ok {
+		return fmt.Errorf("The settings file %s must have a map of settings", fileName)
+	}
+
+	target, hasTarget := file.get("target")
+	switch target.(type) {
+	case *rubyHash:
+	case bool:
+		if target == false {
+			return fmt.Errorf("The settings file %s has a target of false, which never matches", fileName)
+		}
+	default:
+		if hasTarget {
+			return fmt.Errorf("The settings file %s has a target that is not a map or true", fileName)
+		}
+	}
+	return nil
+}
+
+// combineSettings returns the combined settings file the Ruby tool writes for the settings
+// files read from rootFolder, ending with the metadata of the given version.
+func combineSettings(rootFolder string, settingsFiles []*rubyHash, version int) []byte {
+	combined := make(rubyArray, 0, len(settingsFiles)+1)
+	for _, settingsFile := range settingsFiles {
+		combined = append(combined, settingsFile)
+	}
+
+	metadata := &rubyHash{}
+	metadata.set("version", big.NewInt(int64(version)))
+	metadata.set("END", true)
+	meta := &rubyHash{}
+	meta.set("meta", metadata)
+	combined = append(combined, meta)
+
+	document := dumpPsych(combined)
+	return []byte(strings.Replace(document, "\n", "\n"+combinedSettingsHeader(rootFolder), 1))
+}
+
+// combinedSettingsHeader returns the comment the Ruby tool adds after the document start,
+// which names the settings folder by the last part of the root folder path. Like Ruby's
+// split('/').last, trailing slashes are ignored.
+func combinedSettingsHeader(rootFolder string) string {
+	folderName := filepath.Base(filepath.Clean(rootFolder))
+	return "#\n# Don't edit this file directly! It was generated by combine_process_settings from the files in " + folderName + "/settings/.\n#\n"
+}
@@ -0,0 +1,137 @@
+// Command combine_process_settings combines the settings files of a settings folder into the
+// combined process settings file that process_settings loads. It is a Go port of the Ruby
+// bin/combine_process_settings, and emulates how Ruby writes YAML so that the output has
+// the same bytes; see testdata/README.md for how far that has been checked against Ruby:
+//
+//	combine_process_settings -r staging -o staging/combined_process_settings.yml
+//
+// The settings files are read from the settings/ folder under the root folder. When the
+// output file already exists, it is only rewritten if the settings changed, in which case its
+// meta.version is incremented. When it does not exist yet, it is written with the version
+// given with -i.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	process_settings "github.com/Invoca/process_settings.go"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	var rootFolder, output string
+	var initialVersion int
+	var verbose bool
+
+	flags := flag.NewFlagSet("combine_process_settings", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	for _, name := range []string{"r", "root_folder"} {
+		flags.StringVar(&rootFolder, name, "", "The root folder holding the settings/ folder to combine")
+	}
+	for _, name := range []string{"o", "output"} {
+		flags.StringVar(&output, name, "", "The combined settings file to write")
+	}
+	for _, name := range []string{"i", "initial_version"} {
+		flags.IntVar(&initialVersion, name, 0, "The version to write when the output file does not exist yet")
+	}
+	for _, name := range []string{"v", "verbose"} {
+		flags.BoolVar(&verbose, name, false, "Report whether the output file was updated")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if rootFolder == "" || output == "" {
+		flags.Usage()
+		return errors.New("Both the root folder and the output file must be given")
+	}
+
+	updated, version, err := combineSettingsFolder(rootFolder, output, initialVersion)
+	if err != nil {
+		return err
+	}
+	if verbose {
+		if updated {
+			fmt.Fprintf(stdout, "%s: UPDATING to version %d\n", output, version)
+		} else {
+			fmt.Fprintf(stdout, "%s: unchanged\n", output)
+		}
+	}
+	return nil
+}
+
+// combineSettingsFolder combines the settings folder under rootFolder into the output file,
+// and returns whether the file was written and the version it has.
+// The output file is compared with the previous combined file at that path: it is left as
+// it is when the settings did not change, and otherwise written with the next version.
+func combineSettingsFolder(rootFolder, output string, initialVersion int) (bool, int, error) {
+	settingsFiles, err := readSettingsFiles(filepath.Join(rootFolder, "settings"))
+	if err != nil {
+		return false, 0, err
+	}
+
+	version := initialVersion
+	previous, err := loadCombinedSettings(process_settings.FileSource(output))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return false, 0, fmt.Errorf("The previous combined settings file %s cannot be loaded: %w", output, err)
+	default:
+		version = previous.Version()
+	}
+
+	combined := combineSettings(rootFolder, settingsFiles, version)
+	current, err := loadCombinedSettings(process_settings.BytesSource(combined))
+	if err != nil {
+		return false, 0, fmt.Errorf("The combined settings cannot be loaded: %w", err)
+	}
+
+	if previous != nil {
+		if reflect.DeepEqual(withoutMetadata(previous.Settings()), withoutMetadata(current.Settings())) {
+			return false, version, nil
+		}
+		version++
+		combined = combineSettings(rootFolder, settingsFiles, version)
+	}
+
+	if err := writeFileAtomically(output, combined); err != nil {
+		return false, 0, err
+	}
+	return true, version, nil
+}
+
+func loadCombinedSettings(source process_settings.Source) (*process_settings.ProcessSettings, error) {
+	return process_settings.NewProcessSettings(source, nil, process_settings.WithLogger(nil))
+}
+
+// withoutMetadata returns the settings files without the trailing metadata.
+func withoutMetadata(settingsFiles []process_settings.SettingsFile) []process_settings.SettingsFile {
+	return settingsFiles[:len(settingsFiles)-1]
+}
+
+// writeFileAtomically writes the contents to a temporary file next to the file, and renames
+// it over the file, so processes monitoring it never read a partially written file.
+func writeFileAtomically(filePath string, contents []byte) error {
+	tempPath := filePath + ".tmp"
+	if err := os.WriteFile(tempPath, contents, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, filePath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	process_settings "github.com/Invoca/process_settings.go"
+	"github.com/stretchr/testify/assert"
+)
+
+// goldenFilePath is the expected output for testdata/staging; see testdata/README.md for where it came from.
+const goldenFilePath = "testdata/staging/combined_process_settings.yml"
+
+func TestCombineSettingsFolder(t *testing.T) {
+	golden, err := os.ReadFile(goldenFilePath)
+	assert.Nil(t, err)
+
+	rootFolder := copySettingsFolder(t, "testdata/staging")
+	output := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+
+	t.Run("Writes the golden file with the initial version", func(t *testing.T) {
+		updated, version, err := combineSettingsFolder(rootFolder, output, 17)
+
+		assert.Nil(t, err)
+		assert.True(t, updated)
+		assert.Equal(t, 17, version)
+		assertFileContents(t, output, string(golden))
+	})
+
+	t.Run("Names the settings folder in the header when the root folder ends in a slash", func(t *testing.T) {
+		otherOutput := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+
+		_, _, err := combineSettingsFolder(rootFolder+"/", otherOutput, 17)
+
+		assert.Nil(t, err)
+		assertFileContents(t, otherOutput, string(golden))
+	})
+
+	t.Run("Writes a file the process settings can be loaded from", func(t *testing.T) {
+		ps, err := process_settings.NewProcessSettingsFromFile(output, map[string]interface{}{"app": "telecom", "region": "west"}, process_settings.WithLogger(nil))
+		assert.Nil(t, err)
+
+		assert.Equal(t, 17, ps.Version())
+		level, _ := ps.Get("logging", "level")
+		assert.Equal(t, "debug", level)
+		hex, _ := ps.Get("numbers", "hex")
+		assert.Equal(t, 31, hex)
+	})
+
+	t.Run("Leaves the file as it is when the settings did not change", func(t *testing.T) {
+		writeFile(t, filepath.Join(rootFolder, "settings", "honeypot.yml"), "# Only a comment was added\nsettings:\n  honeypot:\n    max_recording_seconds: 600\n    answer_odds: 100\n    status_change_min_days:\n")
+
+		updated, version, err := combineSettingsFolder(rootFolder, output, 1)
+
+		assert.Nil(t, err)
+		assert.False(t, updated)
+		assert.Equal(t, 17, version)
+		assertFileContents(t, output, string(golden))
+	})
+
+	t.Run("Increments the version of the previous file when the settings changed", func(t *testing.T) {
+		writeFile(t, filepath.Join(rootFolder, "settings", "honeypot.yml"), "settings:\n  honeypot:\n    max_recording_seconds: 600\n    answer_odds: 100\n    status_change_min_days:\n")
+		writeFile(t, filepath.Join(rootFolder, "settings", "telecom", "log_level.yml"), "settings:\n  logging:\n    level: info\ntarget:\n  app: telecom\n")
+
+		updated, version, err := combineSettingsFolder(rootFolder, output, 1)
+
+		assert.Nil(t, err)
+		assert.True(t, updated)
+		assert.Equal(t, 18, version)
+		expected := bytes.Replace(golden, []byte("level: debug"), []byte("level: info"), 1)
+		expected = bytes.Replace(expected, []byte("version: 17"), []byte("version: 18"), 1)
+		assertFileContents(t, output, string(expected))
+		_, err = os.Stat(output + ".tmp")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Returns an error when the previous file cannot be loaded", func(t *testing.T) {
+		writeFile(t, output, "--- []\n")
+
+		_, _, err := combineSettingsFolder(rootFolder, output, 1)
+
+		assert.EqualError(t, err, "The previous combined settings file "+output+" cannot be loaded: The settings file does not have the END metadata")
+	})
+}
+
+// TestCombineSettingsFolder_MatchesRubyTool compares the output with the Ruby tool itself.
+// Ruby is not available everywhere the tests run, so it only runs when
+// COMBINE_PROCESS_SETTINGS_RUBY names the bin/combine_process_settings of the gem.
+func TestCombineSettingsFolder_MatchesRubyTool(t *testing.T) {
+	rubyCommand := os.Getenv("COMBINE_PROCESS_SETTINGS_RUBY")
+	if rubyCommand == "" {
+		t.Skip("COMBINE_PROCESS_SETTINGS_RUBY is not set")
+	}
+
+	rootFolder := copySettingsFolder(t, "testdata/staging")
+	rubyOutput := filepath.Join(t.TempDir(), "ruby_combined_process_settings.yml")
+	output := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+
+	rubyResult, err := exec.Command("ruby", rubyCommand, "-r", rootFolder, "-o", rubyOutput, "-i", "17").CombinedOutput()
+	assert.Nil(t, err, string(rubyResult))
+	_, _, err = combineSettingsFolder(rootFolder, output, 17)
+	assert.Nil(t, err)
+
+	rubyContents, err := os.ReadFile(rubyOutput)
+	assert.Nil(t, err)
+	assertFileContents(t, output, string(rubyContents))
+}
+
+func TestReadSettingsFiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		contents      string
+		expectedError string
+	}{
+		{
+			name:          "Returns an error for a file that cannot be parsed",
+			contents:      "settings: [\n",
+			expectedError: "The settings file invalid.yml cannot be parsed: yaml: line 1: did not find expected node content",
+		},
+		{
+			name:          "Returns an error for a file that is not a map",
+			contents:      "- settings\n",
+			expectedError: "The settings file invalid.yml must be a map with target and settings",
+		},
+		{
+			name:          "Returns an error for a file without settings",
+			contents:      "target:\n  app: telecom\n",
+			expectedError: "The settings file invalid.yml must have a map of settings",
+		},
+		{
+			name:          "Returns an error for a file with an unknown key",
+			contents:      "targets:\n  app: telecom\nsettings: {}\n",
+			expectedError: "The settings file invalid.yml has an unknown key targets; only target and settings are allowed",
+		},
+		{
+			name:          "Returns an error for a target of false",
+			contents:      "target: false\nsettings: {}\n",
+			expectedError: "The settings file invalid.yml has a target of false, which never matches",
+		},
+		{
+			name:          "Returns an error for a target that is not a map",
+			contents:      "target: telecom\nsettings: {}\n",
+			expectedError: "The settings file invalid.yml has a target that is not a map or true",
+		},
+		{
+			name:          "Returns an error for a date, which Ruby does not load safely",
+			contents:      "settings:\n  released_on: 2023-01-15\n",
+			expectedError: "The settings file invalid.yml cannot be loaded: line 2: the date 2023-01-15 is not allowed; quote it to make it a string",
+		},
+		{
+			name:          "Returns an error for an alias",
+			contents:      "settings:\n  first: &value 1\n  second: *value\n",
+			expectedError: "The settings file invalid.yml cannot be loaded: line 3: aliases are not allowed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settingsFolder := t.TempDir()
+			writeFile(t, filepath.Join(settingsFolder, "invalid.yml"), test.contents)
+
+			_, err := readSettingsFiles(settingsFolder)
+
+			assert.EqualError(t, err, test.expectedError)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+
+	t.Run("Reports whether the output file was updated when verbose", func(t *testing.T) {
+		var stdout bytes.Buffer
+		err := run([]string{"-r", "testdata/staging", "-o", output, "-i", "17", "-v"}, &stdout, &bytes.Buffer{})
+		assert.Nil(t, err)
+		assert.Equal(t, output+": UPDATING to version 17\n", stdout.String())
+
+		stdout.Reset()
+		err = run([]string{"--root_folder=testdata/staging", "--output=" + output, "--verbose"}, &stdout, &bytes.Buffer{})
+		assert.Nil(t, err)
+		assert.Equal(t, output+": unchanged\n", stdout.String())
+	})
+
+	t.Run("Returns an error when the root folder or output file is missing", func(t *testing.T) {
+		var stderr bytes.Buffer
+		err := run([]string{"-r", "testdata/staging"}, &bytes.Buffer{}, &stderr)
+
+		assert.EqualError(t, err, "Both the root folder and the output file must be given")
+		assert.Contains(t, stderr.String(), "Usage of combine_process_settings")
+	})
+}
+
+// copySettingsFolder copies a root folder from testdata to a temporary directory, so tests can change it.
+// The copy keeps the name of the folder, as it is part of the header of the combined file.
+func copySettingsFolder(t *testing.T, rootFolder string) string {
+	t.Helper()
+	copyFolder := filepath.Join(t.TempDir(), filepath.Base(rootFolder))
+	err := filepath.WalkDir(rootFolder, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, _ := filepath.Rel(rootFolder, filePath)
+		if entry.IsDir() {
+			return os.MkdirAll(filepath.Join(copyFolder, relativePath), 0o755)
+		}
+		contents, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(copyFolder, relativePath), contents, 0o644)
+	})
+	assert.Nil(t, err)
+	return copyFolder
+}
+
+func writeFile(t *testing.T, filePath, contents string) {
+	t.Helper()
+	assert.Nil(t, os.WriteFile(filePath, []byte(contents), 0o644))
+}
+
+func assertFileContents(t *testing.T, filePath, expected string) {
+	t.Helper()
+	contents, err := os.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(contents))
+}
@@ -0,0 +1,758 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The Ruby combine_process_settings loads every settings file with YAML.load_file and dumps
+// the combined settings with to_yaml. To produce the same bytes, the settings files are
+// loaded into the values Psych would load them as, and dumped the way Psych and libyaml
+// would dump those values.
+
+// A rubyHash is a Ruby Hash, which keeps its keys in insertion order.
+type rubyHash struct {
+	keys   []interface{}
+	values []interface{}
+}
+
+// set adds the key, or replaces its value in place when the hash already has the key.
+func (h *rubyHash) set(key, value interface{}) {
+	for i, existing := range h.keys {
+		if rubyValuesEqual(existing, key) {
+			h.values[i] = value
+			return
+		}
+	}
+	h.keys = append(h.keys, key)
+	h.values = append(h.values, value)
+}
+
+func (h *rubyHash) get(key string) (interface{}, bool) {
+	for i, existing := range h.keys {
+		if existing == key {
+			return h.values[i], true
+		}
+	}
+	return nil, false
+}
+
+// A rubyArray is a Ruby Array.
+type rubyArray []interface{}
+
+func rubyValuesEqual(a, b interface{}) bool {
+	if aInt, ok := a.(*big.Int); ok {
+		bInt, ok := b.(*big.Int)
+		return ok && aInt.Cmp(bInt) == 0
+	}
+	return a == b
+}
+
+// loadPsych converts a parsed YAML node into the value Psych's safe loading would return:
+// nil, bool, *big.Int, float64, string, *rubyHash or rubyArray.
+func loadPsych(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return loadPsych(node.Content[0])
+	case yaml.MappingNode:
+		hash := &rubyHash{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, err := loadPsych(node.Content[i])
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case *rubyHash, rubyArray:
+				return nil, fmt.Errorf("line %d: collections are not supported as keys", node.Content[i].Line)
+			}
+			value, err := loadPsych(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			hash.set(key, value)
+		}
+		return hash, nil
+	case yaml.SequenceNode:
+		array := make(rubyArray, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := loadPsych(item)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case yaml.AliasNode:
+		return nil, fmt.Errorf("line %d: aliases are not allowed", node.Line)
+	default:
+		return loadPsychScalar(node)
+	}
+}
+
+func loadPsychScalar(node *yaml.Node) (interface{}, error) {
+	if node.Style&yaml.TaggedStyle != 0 {
+		switch node.Tag {
+		case "!!str":
+			return node.Value, nil
+		case "!!null", "!!bool", "!!int", "!!float":
+		default:
+			return nil, fmt.Errorf("line %d: the tag %s is not allowed", node.Line, node.Tag)
+		}
+	}
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return node.Value, nil
+	}
+
+	value, err := tokenizePsych(node.Value)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	return value, nil
+}
+
+var (
+	psychStringPattern           = regexp.MustCompile(`^[^\d.:-]?[\p{L}_\s!@#$%^&*(){}<>|/\\~;=]+`)
+	psychNotSpecialPattern       = regexp.MustCompile(`(?i)^[^ytonf~]`)
+	psychNullPattern             = regexp.MustCompile(`(?i)^null$`)
+	psychTruePattern             = regexp.MustCompile(`(?i)^(yes|true|on)$`)
+	psychFalsePattern            = regexp.MustCompile(`(?i)^(no|false|off)$`)
+	psychTimePattern             = regexp.MustCompile(`^-?\d{4}-\d{1,2}-\d{1,2}(?:[Tt]|\s+)\d{1,2}:\d\d:\d\d(?:\.\d*)?(?:\s*(?:Z|[-+]\d{1,2}:?(?:\d\d)?))?$`)
+	psychDatePattern             = regexp.MustCompile(`^\d{4}-(?:1[012]|0\d|\d)-(?:[12]\d|3[01]|0\d|\d)$`)
+	psychInfinityPattern         = regexp.MustCompile(`(?i)^\+?\.inf$`)
+	psychNegativeInfinityPattern = regexp.MustCompile(`(?i)^-\.inf$`)
+	psychNaNPattern              = regexp.MustCompile(`(?i)^\.nan$`)
+	psychSymbolPattern           = regexp.MustCompile(`^:.`)
+	psychSexagesimalIntPattern   = regexp.MustCompile(`^[-+]?[0-9][0-9_]*(:[0-5]?[0-9]){1,2}$`)
+	psychSexagesimalFloatPattern = regexp.MustCompile(`^[-+]?[0-9][0-9_]*(:[0-5]?[0-9]){1,2}\.[0-9_]*$`)
+	psychFloatPattern            = regexp.MustCompile(`^(?:[-+]?([0-9][0-9_,]*)?\.[0-9]*([eE][-+][0-9]+)?)$`)
+	psychLoneDotPattern          = regexp.MustCompile(`^[-+]?\.$`)
+	psychFloatDotPattern         = regexp.MustCompile(`\.([Ee]|$)`)
+	psychIntegerPattern          = regexp.MustCompile(`^(?:[-+]?0b[0-1_,]+|[-+]?0[0-7_,]+|[-+]?(?:0|[1-9](?:[0-9]|,[0-9]|_[0-9])*)|[-+]?0x[0-9a-fA-F_,]+)$`)
+)
+
+// tokenizePsych returns the value Psych's ScalarScanner resolves a plain scalar to.
+// Dates, times and symbols are not allowed by Psych's safe loading, so they are errors.
+func tokenizePsych(value string) (interface{}, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if psychStringPattern.MatchString(value) || strings.Contains(value, "\n") {
+		switch {
+		case utf8.RuneCountInString(value) > 5, psychNotSpecialPattern.MatchString(value):
+			return value, nil
+		case value == "~", psychNullPattern.MatchString(value):
+			return nil, nil
+		case psychTruePattern.MatchString(value):
+			return true, nil
+		case psychFalsePattern.MatchString(value):
+			return false, nil
+		default:
+			return value, nil
+		}
+	}
+
+	switch {
+	case psychTimePattern.MatchString(value):
+		return nil, fmt.Errorf("the time %s is not allowed; quote it to make it a string", value)
+	case psychDatePattern.MatchString(value):
+		// Psych leaves strings that look like dates but are not valid dates as strings.
+		if _, err := time.Parse("2006-1-2", value); err != nil {
+			return value, nil
+		}
+		return nil, fmt.Errorf("the date %s is not allowed; quote it to make it a string", value)
+	case psychInfinityPattern.MatchString(value):
+		return math.Inf(1), nil
+	case psychNegativeInfinityPattern.MatchString(value):
+		return math.Inf(-1), nil
+	case psychNaNPattern.MatchString(value):
+		return math.NaN(), nil
+	case psychSymbolPattern.MatchString(value):
+		return nil, fmt.Errorf("the symbol %s is not allowed; quote it to make it a string", value)
+	case psychSexagesimalIntPattern.MatchString(value):
+		return sexagesimalInt(value), nil
+	case psychSexagesimalFloatPattern.MatchString(value):
+		return sexagesimalFloat(value), nil
+	case psychFloatPattern.MatchString(value):
+		if psychLoneDotPattern.MatchString(value) {
+			return value, nil
+		}
+		cleaned := psychFloatDotPattern.ReplaceAllString(strings.NewReplacer(",", "", "_", "").Replace(value), "$1")
+		// Like Ruby, floats that are out of range become infinite or zero.
+		float, err := strconv.ParseFloat(cleaned, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return nil, fmt.Errorf("invalid float %s", value)
+		}
+		return float, nil
+	case psychIntegerPattern.MatchString(value):
+		integer, ok := new(big.Int).SetString(strings.NewReplacer(",", "", "_", "").Replace(value), 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %s", value)
+		}
+		return integer, nil
+	default:
+		return value, nil
+	}
+}
+
+// sexagesimalInt and sexagesimalFloat reproduce Psych's conversion of base 60 numbers,
+// which weighs the parts by 60 ** |index - 2|.
+func sexagesimalInt(value string) *big.Int {
+	sum := new(big.Int)
+	for i, part := range strings.Split(value, ":") {
+		digits := strings.ReplaceAll(part, "_", "")
+		n, _ := new(big.Int).SetString(strings.TrimPrefix(digits, "+"), 10)
+		weight := new(big.Int).Exp(big.NewInt(60), big.NewInt(int64(absInt(i-2))), nil)
+		sum.Add(sum, n.Mul(n, weight))
+	}
+	return sum
+}
+
+func sexagesimalFloat(value string) float64 {
+	sum := 0.0
+	for i, part := range strings.Split(value, ":") {
+		n, _ := strconv.ParseFloat(strings.ReplaceAll(part, "_", ""), 64)
+		sum += n * math.Pow(60, float64(absInt(i-2)))
+	}
+	return sum
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// formatRubyFloat formats a float the way Ruby's Float#to_s does.
+func formatRubyFloat(value float64) string {
+	if value == 0 {
+		if math.Signbit(value) {
+			return "-0.0"
+		}
+		return "0.0"
+	}
+
+	// The shortest digits that round trip, and the position of the decimal point.
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(value, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	decimalPoint, _ := strconv.Atoi(exponent)
+	decimalPoint++
+
+	switch {
+	case decimalPoint > 0 && decimalPoint <= 16:
+		if len(digits) <= decimalPoint {
+			return sign + digits + strings.Repeat("0", decimalPoint-len(digits)) + ".0"
+		}
+		return sign + digits[:decimalPoint] + "." + digits[decimalPoint:]
+	case decimalPoint <= 0 && decimalPoint > -4:
+		return sign + "0." + strings.Repeat("0", -decimalPoint) + digits
+	default:
+		fraction := digits[1:]
+		if fraction == "" {
+			fraction = "0"
+		}
+		return fmt.Sprintf("%s%s.%se%+03d", sign, digits[:1], fraction, decimalPoint-1)
+	}
+}
+
+// The width past which libyaml folds long scalars at a space, and the indentation it uses.
+const (
+	psychBestWidth  = 80
+	psychBestIndent = 2
+)
+
+// A psychEmitter writes values in the block style of Psych's to_yaml, tracking the
+// column and whitespace state the way libyaml's emitter does.
+type psychEmitter struct {
+	out        strings.Builder
+	column     int
+	whitespace bool // Whether the last character written was whitespace
+	indention  bool // Whether only indentation has been written on the current line
+}
+
+// dumpPsych returns the document Psych's to_yaml writes for value.
+func dumpPsych(value interface{}) string {
+	emitter := &psychEmitter{whitespace: true, indention: true}
+	emitter.writeIndicator("---", true)
+	emitter.emitNode(value, -1, false)
+	emitter.writeIndent(0)
+	return emitter.out.String()
+}
+
+// emitNode writes value as a block node. indent is the indentation of the collection
+// holding it, and inMapping whether it is the value of a mapping entry.
+func (e *psychEmitter) emitNode(value interface{}, indent int, inMapping bool) {
+	switch value := value.(type) {
+	case *rubyHash:
+		if len(value.keys) == 0 {
+			e.writeIndicator("{}", true)
+			return
+		}
+		e.emitMapping(value, e.increaseIndent(indent, false))
+	case rubyArray:
+		if len(value) == 0 {
+			e.writeIndicator("[]", true)
+			return
+		}
+		// A sequence that is the value of a mapping entry is not indented any further than its key.
+		e.emitSequence(value, e.increaseIndent(indent, inMapping && !e.indention))
+	default:
+		e.emitScalar(value, e.increaseIndent(indent, false), false)
+	}
+}
+
+func (e *psychEmitter) increaseIndent(indent int, indentless bool) int {
+	switch {
+	case indent < 0:
+		return 0
+	case indentless:
+		return indent
+	default:
+		return indent + psychBestIndent
+	}
+}
+
+func (e *psychEmitter) emitMapping(hash *rubyHash, indent int) {
+	for i, key := range hash.keys {
+		e.writeIndent(indent)
+		e.emitScalar(key, indent, true)
+		e.writeIndicator(":", false)
+		e.emitNode(hash.values[i], indent, true)
+	}
+}
+
+func (e *psychEmitter) emitSequence(array rubyArray, indent int) {
+	for _, item := range array {
+		e.writeIndent(indent)
+		e.writeIndicator("-", true)
+		e.indention = true
+		e.emitNode(item, indent, false)
+	}
+}
+
+// emitScalar writes a scalar in the style libyaml picks for the style Psych asks for.
+// Simple keys are never folded.
+func (e *psychEmitter) emitScalar(value interface{}, indent int, simpleKey bool) {
+	text, style := psychScalar(value)
+	analysis := analyzeScalar(text)
+
+	if style == psychPlain {
+		if !analysis.blockPlainAllowed || (text == "" && simpleKey) {
+			style = psychSingleQuoted
+		}
+	}
+	if style == psychSingleQuoted && !analysis.singleQuotedAllowed {
+		style = psychDoubleQuoted
+	}
+	if style == psychLiteral && (!analysis.blockAllowed || simpleKey) {
+		style = psychDoubleQuoted
+	}
+	if simpleKey && analysis.multiline {
+		style = psychDoubleQuoted
+	}
+
+	switch style {
+	case psychPlain:
+		e.writePlain(text, indent, !simpleKey)
+	case psychSingleQuoted:
+		e.writeSingleQuoted(text, indent, !simpleKey)
+	case psychDoubleQuoted:
+		e.writeDoubleQuoted(text, indent, !simpleKey)
+	default:
+		e.writeLiteral(text, indent)
+	}
+}
+
+type psychScalarStyle int
+
+const (
+	psychPlain psychScalarStyle = iota
+	psychSingleQuoted
+	psychDoubleQuoted
+	psychLiteral
+)
+
+var (
+	psychLeadingNonWordPattern = regexp.MustCompile(`^[^\p{L}\p{M}\p{N}\p{Pc}][^"]*$`)
+	psychOctalLookalikePattern = regexp.MustCompile(`^0[0-7]*[89]`)
+)
+
+// psychScalar returns the text of a scalar and the style Psych's YAMLTree asks libyaml for.
+func psychScalar(value interface{}) (string, psychScalarStyle) {
+	switch value := value.(type) {
+	case nil:
+		return "", psychPlain
+	case bool:
+		return strconv.FormatBool(value), psychPlain
+	case *big.Int:
+		return value.String(), psychPlain
+	case float64:
+		switch {
+		case math.IsNaN(value):
+			return ".nan", psychPlain
+		case math.IsInf(value, 1):
+			return ".inf", psychPlain
+		case math.IsInf(value, -1):
+			return "-.inf", psychPlain
+		default:
+			return formatRubyFloat(value), psychPlain
+		}
+	case string:
+		return value, psychStringStyle(value)
+	default:
+		panic(fmt.Sprintf("unexpected value %#v", value))
+	}
+}
+
+// psychStringStyle picks the style of a string the way Psych's visit_String does.
+func psychStringStyle(value string) psychScalarStyle {
+	if index := strings.Index(value, "\n"); index >= 0 && !isEndOfString(value[index+1:]) {
+		return psychLiteral
+	}
+	switch {
+	case value == "<<":
+		return psychSingleQuoted
+	case value == "y" || value == "Y" || value == "n" || value == "N":
+		return psychDoubleQuoted
+	case matchesRubyLine(psychLeadingNonWordPattern, value):
+		return psychDoubleQuoted
+	}
+	if _, isString := mustTokenizePsych(value).(string); !isString || psychOctalLookalikePattern.MatchString(value) {
+		return psychSingleQuoted
+	}
+	return psychPlain
+}
+
+// isEndOfString reports whether rest matches Ruby's \Z: the end, or a final newline.
+func isEndOfString(rest string) bool {
+	return rest == "" || rest == "\n"
+}
+
+// matchesRubyLine matches a pattern anchored with ^ and $ the way Ruby does, where $ also
+// matches before a final newline. The strings it is used with have at most that one newline.
+func matchesRubyLine(pattern *regexp.Regexp, value string) bool {
+	return pattern.MatchString(value) || (strings.HasSuffix(value, "\n") && pattern.MatchString(strings.TrimSuffix(value, "\n")))
+}
+
+// mustTokenizePsych returns what a string would load as if it were written plain.
+// Values that fail to load, like dates, are not strings either.
+func mustTokenizePsych(value string) interface{} {
+	token, err := tokenizePsych(value)
+	if err != nil {
+		return nil
+	}
+	return token
+}
+
+// A scalarAnalysis holds which styles libyaml allows for a scalar in block context.
+type scalarAnalysis struct {
+	multiline           bool
+	blockPlainAllowed   bool
+	singleQuotedAllowed bool
+	blockAllowed        bool
+}
+
+// analyzeScalar is a port of libyaml's yaml_emitter_analyze_scalar for block context.
+func analyzeScalar(value string) scalarAnalysis {
+	if value == "" {
+		return scalarAnalysis{blockPlainAllowed: true, singleQuotedAllowed: true}
+	}
+
+	runes := []rune(value)
+	var (
+		blockIndicators, lineBreaks, specialCharacters           bool
+		leadingSpace, leadingBreak, trailingSpace, trailingBreak bool
+		breakSpace, spaceBreak, previousSpace, previousBreak     bool
+	)
+	if strings.HasPrefix(value, "---") || strings.HasPrefix(value, "...") {
+		blockIndicators = true
+	}
+
+	precededByWhitespace := true
+	followedByWhitespace := len(runes) < 2 || isBlank(runes[1])
+	for i, r := range runes {
+		first, last := i == 0, i == len(runes)-1
+		if first {
+			switch r {
+			case '#', ',', '[', ']', '{', '}', '&', '*', '!', '|', '>', '\'', '"', '%', '@', '`':
+				blockIndicators = true
+			case '?', ':', '-':
+				if followedByWhitespace {
+					blockIndicators = true
+				}
+			}
+		} else {
+			switch {
+			case r == ':' && followedByWhitespace:
+				blockIndicators = true
+			case r == '#' && precededByWhitespace:
+				blockIndicators = true
+			}
+		}
+
+		if !isPrintable(r) {
+			specialCharacters = true
+		}
+		if isBreak(r) {
+			lineBreaks = true
+		}
+
+		switch {
+		case r == ' ':
+			leadingSpace = leadingSpace || first
+			trailingSpace = trailingSpace || last
+			breakSpace = breakSpace || previousBreak
+			previousSpace, previousBreak = true, false
+		case isBreak(r):
+			leadingBreak = leadingBreak || first
+			trailingBreak = trailingBreak || last
+			spaceBreak = spaceBreak || previousSpace
+			previousSpace, previousBreak = false, true
+		default:
+			previousSpace, previousBreak = false, false
+		}
+
+		precededByWhitespace = isBlank(r)
+		if !last {
+			followedByWhitespace = i+2 >= len(runes) || isBlank(runes[i+2])
+		}
+	}
+
+	analysis := scalarAnalysis{multiline: lineBreaks, blockPlainAllowed: true, singleQuotedAllowed: true, blockAllowed: true}
+	if leadingSpace || leadingBreak || trailingSpace || trailingBreak {
+		analysis.blockPlainAllowed = false
+	}
+	if trailingSpace {
+		analysis.blockAllowed = false
+	}
+	if breakSpace {
+		analysis.blockPlainAllowed = false
+		analysis.singleQuotedAllowed = false
+	}
+	if spaceBreak || specialCharacters {
+		analysis = scalarAnalysis{multiline: lineBreaks}
+	}
+	if lineBreaks || blockIndicators {
+		analysis.blockPlainAllowed = false
+	}
+	return analysis
+}
+
+func isBreak(r rune) bool {
+	return r == '\r' || r == '\n' || r == '\u0085' || r == '\u2028' || r == '\u2029'
+}
+
+func isBlank(r rune) bool {
+	return r == ' ' || r == '\t' || isBreak(r)
+}
+
+// isPrintable matches libyaml's IS_PRINTABLE, which notably excludes characters outside
+// the Basic Multilingual Plane.
+func isPrintable(r rune) bool {
+	return r == '\n' ||
+		(r >= 0x20 && r <= 0x7E) ||
+		(r >= 0xA0 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD && r != 0xFEFF)
+}
+
+func (e *psychEmitter) put(r rune) {
+	e.out.WriteRune(r)
+	if r == '\n' {
+		e.column = 0
+	} else {
+		e.column++
+	}
+}
+
+func (e *psychEmitter) write(text string) {
+	for _, r := range text {
+		e.put(r)
+	}
+}
+
+func (e *psychEmitter) writeIndicator(indicator string, needWhitespace bool) {
+	if needWhitespace && !e.whitespace {
+		e.put(' ')
+	}
+	e.write(indicator)
+	e.whitespace = false
+	e.indention = false
+}
+
+func (e *psychEmitter) writeIndent(indent int) {
+	if !e.indention || e.column > indent || (e.column == indent && !e.whitespace) {
+		e.put('\n')
+	}
+	for e.column < indent {
+		e.put(' ')
+	}
+	e.whitespace = true
+	e.indention = true
+}
+
+func (e *psychEmitter) writePlain(text string, indent int, allowBreaks bool) {
+	// libyaml leaves out the space before an empty value, so nil values have no trailing space.
+	if !e.whitespace && text != "" {
+		e.put(' ')
+	}
+
+	runes := []rune(text)
+	spaces := false
+	for i, r := range runes {
+		if r == ' ' {
+			if allowBreaks && !spaces && e.column > psychBestWidth && !(i+1 < len(runes) && runes[i+1] == ' ') {
+				e.writeIndent(indent)
+			} else {
+				e.put(r)
+			}
+			spaces = true
+		} else {
+			e.put(r)
+			e.indention = false
+			spaces = false
+		}
+	}
+	e.whitespace = false
+	e.indention = false
+}
+
+func (e *psychEmitter) writeSingleQuoted(text string, indent int, allowBreaks bool) {
+	e.writeIndicator("'", true)
+
+	runes := []rune(text)
+	spaces, breaks := false, false
+	for i, r := range runes {
+		switch {
+		case r == ' ':
+			if allowBreaks && !spaces && e.column > psychBestWidth && i != 0 && i != len(runes)-1 && runes[i+1] != ' ' {
+				e.writeIndent(indent)
+			} else {
+				e.put(r)
+			}
+			spaces = true
+		case isBreak(r):
+			if !breaks && r == '\n' {
+				e.put('\n')
+			}
+			e.putBreak(r)
+			e.indention = true
+			breaks = true
+		default:
+			if breaks {
+				e.writeIndent(indent)
+			}
+			if r == '\'' {
+				e.put('\'')
+			}
+			e.put(r)
+			e.indention = false
+			spaces, breaks = false, false
+		}
+	}
+	if breaks {
+		e.writeIndent(indent)
+	}
+
+	e.writeIndicator("'", false)
+}
+
+var psychEscapes = map[rune]rune{
+	0x00: '0', '\a': 'a', '\b': 'b', '\t': 't', '\n': 'n', '\v': 'v', '\f': 'f', '\r': 'r',
+	0x1B: 'e', '"': '"', '\\': '\\', 0x85: 'N', 0xA0: '_', 0x2028: 'L', 0x2029: 'P',
+}
+
+func (e *psychEmitter) writeDoubleQuoted(text string, indent int, allowBreaks bool) {
+	e.writeIndicator(`"`, true)
+
+	runes := []rune(text)
+	spaces := false
+	for i, r := range runes {
+		switch {
+		case !isPrintable(r) || r == 0xFEFF || isBreak(r) || r == '"' || r == '\\':
+			e.put('\\')
+			if escape, ok := psychEscapes[r]; ok {
+				e.put(escape)
+			} else if r <= 0xFF {
+				e.write(fmt.Sprintf("x%02X", r))
+			} else if r <= 0xFFFF {
+				e.write(fmt.Sprintf("u%04X", r))
+			} else {
+				e.write(fmt.Sprintf("U%08X", r))
+			}
+			spaces = false
+		case r == ' ':
+			if allowBreaks && !spaces && e.column > psychBestWidth && i != 0 && i != len(runes)-1 {
+				e.writeIndent(indent)
+				if runes[i+1] == ' ' {
+					e.put('\\')
+				}
+			} else {
+				e.put(r)
+			}
+			spaces = true
+		default:
+			e.put(r)
+			spaces = false
+		}
+	}
+
+	e.writeIndicator(`"`, false)
+}
+
+func (e *psychEmitter) writeLiteral(text string, indent int) {
+	e.writeIndicator("|", true)
+	runes := []rune(text)
+	if isBlank(runes[0]) && runes[0] != '\t' {
+		e.write(strconv.Itoa(psychBestIndent))
+	}
+	switch {
+	case !isBreak(runes[len(runes)-1]):
+		e.put('-')
+	case len(runes) == 1 || isBreak(runes[len(runes)-2]):
+		e.put('+')
+	}
+	e.put('\n')
+	e.indention = true
+	e.whitespace = true
+
+	breaks := true
+	for _, r := range runes {
+		if isBreak(r) {
+			e.putBreak(r)
+			e.indention = true
+			breaks = true
+		} else {
+			if breaks {
+				e.writeIndent(indent)
+			}
+			e.put(r)
+			e.indention = false
+			breaks = false
+		}
+	}
+}
+
+// putBreak writes a line break the way libyaml's WRITE_BREAK does, which writes \n for a \n
+// and keeps any other line break as it is.
+func (e *psychEmitter) putBreak(r rune) {
+	e.out.WriteRune(r)
+	e.column = 0
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTokenizePsych(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected interface{}
+	}{
+		{"", nil},
+		{"~", nil},
+		{"Null", nil},
+		{"yes", true},
+		{"On", true},
+		{"off", false},
+		{"no", false},
+		{"y", "y"},
+		{"nothing", "nothing"},
+		{"42", big.NewInt(42)},
+		{"-42", big.NewInt(-42)},
+		{"1_000", big.NewInt(1000)},
+		{"1,000", big.NewInt(1000)},
+		{"0x1F", big.NewInt(31)},
+		{"0b101", big.NewInt(5)},
+		{"017", big.NewInt(15)},
+		{"0789", "0789"},
+		{"1:30", big.NewInt(5400)},
+		{"1.5", 1.5},
+		{"1.", 1.0},
+		{".5", 0.5},
+		{"1.0e+20", 1e20},
+		{".", "."},
+		{"1.2.3", "1.2.3"},
+		{"1e5", "1e5"},
+		{".inf", math.Inf(1)},
+		{"-.Inf", math.Inf(-1)},
+		{"2023-02-30", "2023-02-30"},
+		{"+18053334444", big.NewInt(18053334444)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			value, err := tokenizePsych(test.value)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+
+	t.Run("Returns an error for values Psych does not load safely", func(t *testing.T) {
+		for _, value := range []string{"2023-01-15", "2023-01-15 10:00:00 Z", ":symbol"} {
+			_, err := tokenizePsych(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestFormatRubyFloat(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{3, "3.0"},
+		{-0.25, "-0.25"},
+		{0, "0.0"},
+		{math.Copysign(0, -1), "-0.0"},
+		{123456.789, "123456.789"},
+		{1e15, "1000000000000000.0"},
+		{1e16, "1.0e+16"},
+		{1.5e20, "1.5e+20"},
+		{0.0001, "0.0001"},
+		{0.00001, "1.0e-05"},
+		{1.25e-7, "1.25e-07"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			assert.Equal(t, test.expected, formatRubyFloat(test.value))
+		})
+	}
+}
+
+func TestDumpPsych(t *testing.T) {
+	hash := func(keysAndValues ...interface{}) *rubyHash {
+		h := &rubyHash{}
+		for i := 0; i < len(keysAndValues); i += 2 {
+			h.set(keysAndValues[i], keysAndValues[i+1])
+		}
+		return h
+	}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{
+			name:     "Writes nil without a trailing space",
+			value:    hash("key", nil, "list", rubyArray{nil, "value"}),
+			expected: "---\nkey:\nlist:\n-\n- value\n",
+		},
+		{
+			name:     "Writes keys that are not strings",
+			value:    hash(big.NewInt(1), "one", true, "yes", "", "empty"),
+			expected: "---\n1: one\ntrue: 'yes'\n'': empty\n",
+		},
+		{
+			name:     "Replaces the value of a key that is set again in place",
+			value:    hash("first", 1.0, "second", 2.0, "first", 3.0),
+			expected: "---\nfirst: 3.0\nsecond: 2.0\n",
+		},
+		{
+			name:     "Escapes characters outside the Basic Multilingual Plane",
+			value:    rubyArray{"smile 😀"},
+			expected: "---\n- \"smile \\U0001F600\"\n",
+		},
+		{
+			name:     "Writes strings with line breaks as literal blocks",
+			value:    hash("text", "first\n\nthird\n"),
+			expected: "---\ntext: |\n  first\n\n  third\n",
+		},
+		{
+			name:     "Nests sequences in sequences",
+			value:    rubyArray{rubyArray{big.NewInt(1), rubyArray{big.NewInt(2)}}, rubyArray{}, &rubyHash{}},
+			expected: "---\n- - 1\n  - - 2\n- []\n- {}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, dumpPsych(test.value))
+		})
+	}
+
+	t.Run("Writes the combined settings file of the library tests byte for byte", func(t *testing.T) {
+		// Unlike the golden file in testdata/staging, this file was not written by this port:
+		// it is the combined settings file the library tests use, in the format of the Ruby tool.
+		contents, err := os.ReadFile("../../testdata/combined_process_settings.yml")
+		assert.Nil(t, err)
+		var document yaml.Node
+		assert.Nil(t, yaml.Unmarshal(contents, &document))
+		value, err := loadPsych(&document)
+		assert.Nil(t, err)
+
+		dumped := strings.Replace(dumpPsych(value), "\n", "\n"+combinedSettingsHeader("staging"), 1)
+		assert.Equal(t, string(contents), dumped)
+	})
+}
@@ -0,0 +1,19 @@
+#!/usr/bin/env bash
+# Regenerates staging/combined_process_settings.yml with the Ruby combine_process_settings
+# from the process_settings gem, and records the versions it was generated with in
+# staging/GENERATED_WITH. Run it with the gem installed, then run the Go tests.
+set -euo pipefail
+
+cd "$(dirname "$0")"
+
+ruby_command="${COMBINE_PROCESS_SETTINGS_RUBY:-$(dirname "$(gem which process_settings)")/../bin/combine_process_settings}"
+
+# The Ruby tool increments the version of an existing output file, so start from scratch.
+rm -f staging/combined_process_settings.yml
+ruby "$ruby_command" -r staging -o staging/combined_process_settings.yml -i 17
+
+{
+  ruby -rpsych -e 'puts "ruby #{RUBY_VERSION}", "psych #{Psych::VERSION}", "libyaml #{Psych::LIBYAML_VERSION}"'
+  gem list --exact process_settings | grep process_settings
+  echo "ruby $ruby_command -r staging -o staging/combined_process_settings.yml -i 17"
+} > staging/GENERATED_WITH
@@ -0,0 +1,69 @@
+package process_settings
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Getter looks up the value of a setting. It is implemented by ProcessSettings
+// and DynamicContextView.
+type Getter interface {
+	Get(settingPath ...string) (interface{}, error)
+}
+
+// GetAs returns the value of a setting converted to the type T.
+// Values that are already of type T are returned as is, anything else is
+// decoded the same way as Decode.
+func GetAs[T any](settings Getter, settingPath ...string) (T, error) {
+	var target T
+
+	value, err := settings.Get(settingPath...)
+	if err != nil {
+		return target, err
+	}
+
+	if typedValue, ok := value.(T); ok {
+		return typedValue, nil
+	}
+
+	err = decodeValue(value, settingPath, &target)
+	return target, err
+}
+
+// Decode finds the subtree of settings at the given path and unmarshals it into
+// target, which must be a non-nil pointer; a SettingConversionError is returned otherwise.
+// Struct fields are matched using their yaml tags, just like they would be when
+// unmarshalling the settings file directly.
+func (ps *ProcessSettings) Decode(settingPath []string, target interface{}) error {
+	value, err := ps.Get(settingPath...)
+	if err != nil {
+		return err
+	}
+	return decodeValue(value, settingPath, target)
+}
+
+// errInvalidDecodeTarget is the underlying error of the SettingConversionError returned
+// when the target of Decode is not a non-nil pointer.
+var errInvalidDecodeTarget = errors.New("The target must be a non-nil pointer")
+
+// decodeValue re-encodes the value as a yaml node and decodes that node into the target,
+// so the values keep the types that yaml.v3 originally decoded them as.
+func decodeValue(value interface{}, settingPath []string, target interface{}) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+		return &SettingConversionError{settingPath, value, fmt.Sprintf("%T", target), errInvalidDecodeTarget}
+	}
+	targetType := targetValue.Elem().Type().String()
+
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return &SettingConversionError{settingPath, value, targetType, err}
+	}
+	if err := node.Decode(target); err != nil {
+		return &SettingConversionError{settingPath, value, targetType, err}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package process_settings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type frontendConfig struct {
+	LogLevel   string   `yaml:"log_level"`
+	Workers    int      `yaml:"workers"`
+	SampleRate float64  `yaml:"sample_rate"`
+	Domains    []string `yaml:"domains"`
+	Cache      struct {
+		Enabled bool `yaml:"enabled"`
+	} `yaml:"cache"`
+}
+
+var frontendSettings = newProcessSettings(nil, []SettingsFile{
+	{
+		FileName: "frontend.yml",
+		Settings: map[string]interface{}{
+			"frontend": map[string]interface{}{
+				"log_level":   "info",
+				"workers":     4,
+				"sample_rate": 0.5,
+				"domains":     []interface{}{"example.com", "microsite.example.com"},
+				"cache": map[string]interface{}{
+					"enabled": true,
+				},
+			},
+		},
+	},
+}, nil)
+
+func TestProcessSettings_Decode(t *testing.T) {
+	t.Run("Decodes a subtree into a struct using its yaml tags", func(t *testing.T) {
+		var config frontendConfig
+		err := frontendSettings.Decode([]string{"frontend"}, &config)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "info", config.LogLevel)
+		assert.Equal(t, 4, config.Workers)
+		assert.Equal(t, 0.5, config.SampleRate)
+		assert.Equal(t, []string{"example.com", "microsite.example.com"}, config.Domains)
+		assert.True(t, config.Cache.Enabled)
+	})
+
+	t.Run("Returns not found errors unchanged", func(t *testing.T) {
+		var config frontendConfig
+		err := frontendSettings.Decode([]string{"backend"}, &config)
+
+		assert.Equal(t, "The setting 'backend' was not found", err.Error())
+	})
+
+	t.Run("Returns a conversion error when the subtree does not fit the target", func(t *testing.T) {
+		var workers []int
+		err := frontendSettings.Decode([]string{"frontend", "workers"}, &workers)
+
+		var conversionError *SettingConversionError
+		assert.ErrorAs(t, err, &conversionError)
+		assert.Equal(t, []string{"frontend", "workers"}, conversionError.SettingPath)
+		assert.Equal(t, "[]int", conversionError.TargetType)
+		assert.NotNil(t, conversionError.Err)
+	})
+
+	t.Run("Returns a conversion error when the target is not a non-nil pointer", func(t *testing.T) {
+		var nilConfig *frontendConfig
+		tests := []struct {
+			name         string
+			target       interface{}
+			expectedType string
+		}{
+			{"nil", nil, "<nil>"},
+			{"nil pointer", nilConfig, "*process_settings.frontendConfig"},
+			{"struct", frontendConfig{}, "process_settings.frontendConfig"},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				err := frontendSettings.Decode([]string{"frontend"}, test.target)
+
+				var conversionError *SettingConversionError
+				assert.ErrorAs(t, err, &conversionError)
+				assert.Equal(t, test.expectedType, conversionError.TargetType)
+				assert.ErrorIs(t, err, errInvalidDecodeTarget)
+			})
+		}
+	})
+}
+
+func TestGetAs(t *testing.T) {
+	t.Run("Returns values that already have the requested type", func(t *testing.T) {
+		value, err := GetAs[string](frontendSettings, "frontend", "log_level")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "info", value)
+	})
+
+	t.Run("Decodes values into the requested type", func(t *testing.T) {
+		value, err := GetAs[frontendConfig](frontendSettings, "frontend")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 4, value.Workers)
+	})
+
+	t.Run("Keeps the original numeric types", func(t *testing.T) {
+		value, err := GetAs[map[string]interface{}](frontendSettings, "frontend", "cache")
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]interface{}{"enabled": true}, value)
+
+		workers, err := GetAs[int64](frontendSettings, "frontend", "workers")
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(4), workers)
+	})
+
+	t.Run("Returns a conversion error when the value does not fit the type", func(t *testing.T) {
+		_, err := GetAs[int](frontendSettings, "frontend", "log_level")
+
+		var conversionError *SettingConversionError
+		assert.ErrorAs(t, err, &conversionError)
+		assert.Equal(t, "int", conversionError.TargetType)
+	})
+}
@@ -0,0 +1,84 @@
+package process_settings
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A SettingChange is a single difference between two versions of the settings.
+type SettingChange struct {
+	Path     string      // The dot delimited path of the setting
+	OldValue interface{} // The value before the change, nil when the setting was added
+	NewValue interface{} // The value after the change, nil when the setting was removed
+}
+
+// A SettingsDiff lists the differences between two versions of the effective settings,
+// each sorted by path. A map that was added or removed as a whole is listed once, at its
+// own path; maps present in both versions are compared key by key.
+type SettingsDiff struct {
+	Added   []SettingChange
+	Removed []SettingChange
+	Changed []SettingChange
+}
+
+// IsEmpty reports whether the two versions of the settings were the same.
+func (d SettingsDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String returns the diff with one line per change, suitable for logging.
+func (d SettingsDiff) String() string {
+	lines := make([]string, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	for _, change := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s: %v", change.Path, change.NewValue))
+	}
+	for _, change := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s: %v", change.Path, change.OldValue))
+	}
+	for _, change := range d.Changed {
+		lines = append(lines, fmt.Sprintf("~ %s: %v => %v", change.Path, change.OldValue, change.NewValue))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diff compares two versions of the effective settings, as returned by
+// ProcessSettings.EffectiveSettings, and returns the settings that were added,
+// removed and changed.
+func Diff(old, new map[string]interface{}) SettingsDiff {
+	var diff SettingsDiff
+	diffMaps(nil, old, new, &diff)
+
+	for _, changes := range [][]SettingChange{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	}
+	return diff
+}
+
+func diffMaps(settingPath []string, old, new map[string]interface{}, diff *SettingsDiff) {
+	for key, oldValue := range old {
+		keyPath := append(settingPath[:len(settingPath):len(settingPath)], key)
+
+		newValue, keyExists := new[key]
+		if !keyExists {
+			diff.Removed = append(diff.Removed, SettingChange{dotDelimitedSettingsPath(keyPath), oldValue, nil})
+			continue
+		}
+
+		oldMap, oldIsMap := oldValue.(map[string]interface{})
+		newMap, newIsMap := newValue.(map[string]interface{})
+		if oldIsMap && newIsMap {
+			diffMaps(keyPath, oldMap, newMap, diff)
+		} else if !reflect.DeepEqual(oldValue, newValue) {
+			diff.Changed = append(diff.Changed, SettingChange{dotDelimitedSettingsPath(keyPath), oldValue, newValue})
+		}
+	}
+
+	for key, newValue := range new {
+		if _, keyExists := old[key]; !keyExists {
+			keyPath := append(settingPath[:len(settingPath):len(settingPath)], key)
+			diff.Added = append(diff.Added, SettingChange{dotDelimitedSettingsPath(keyPath), nil, newValue})
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package process_settings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name         string
+		old          map[string]interface{}
+		new          map[string]interface{}
+		expectedDiff SettingsDiff
+	}{
+		{
+			name:         "Returns an empty diff when the settings are the same",
+			old:          map[string]interface{}{"honeypot": map[string]interface{}{"answer_odds": 100}},
+			new:          map[string]interface{}{"honeypot": map[string]interface{}{"answer_odds": 100}},
+			expectedDiff: SettingsDiff{},
+		},
+		{
+			name: "Returns the added, removed and changed leaves of nested maps",
+			old: map[string]interface{}{
+				"honeypot": map[string]interface{}{
+					"answer_odds": 100,
+					"log_stream":  "sip",
+				},
+			},
+			new: map[string]interface{}{
+				"honeypot": map[string]interface{}{
+					"answer_odds":           50,
+					"max_recording_seconds": 600,
+				},
+			},
+			expectedDiff: SettingsDiff{
+				Added:   []SettingChange{{Path: "honeypot.max_recording_seconds", NewValue: 600}},
+				Removed: []SettingChange{{Path: "honeypot.log_stream", OldValue: "sip"}},
+				Changed: []SettingChange{{Path: "honeypot.answer_odds", OldValue: 100, NewValue: 50}},
+			},
+		},
+		{
+			name: "Returns maps that were added or removed as a whole at their own path",
+			old:  map[string]interface{}{"honeypot": map[string]interface{}{"answer_odds": 100}},
+			new:  map[string]interface{}{"logging": map[string]interface{}{"level": "debug"}},
+			expectedDiff: SettingsDiff{
+				Added:   []SettingChange{{Path: "logging", NewValue: map[string]interface{}{"level": "debug"}}},
+				Removed: []SettingChange{{Path: "honeypot", OldValue: map[string]interface{}{"answer_odds": 100}}},
+			},
+		},
+		{
+			name: "Returns a change when a map is replaced by a value",
+			old:  map[string]interface{}{"log_stream": map[string]interface{}{"sip": "original"}},
+			new:  map[string]interface{}{"log_stream": nil},
+			expectedDiff: SettingsDiff{
+				Changed: []SettingChange{{Path: "log_stream", OldValue: map[string]interface{}{"sip": "original"}, NewValue: nil}},
+			},
+		},
+		{
+			name: "Compares lists as a whole",
+			old:  map[string]interface{}{"caller_ids": []interface{}{"+18053334444"}},
+			new:  map[string]interface{}{"caller_ids": []interface{}{"+18053334444", "+12755554321"}},
+			expectedDiff: SettingsDiff{
+				Changed: []SettingChange{{Path: "caller_ids", OldValue: []interface{}{"+18053334444"}, NewValue: []interface{}{"+18053334444", "+12755554321"}}},
+			},
+		},
+		{
+			name: "Sorts the changes by path",
+			old:  map[string]interface{}{},
+			new:  map[string]interface{}{"c": 3, "a": 1, "b": map[string]interface{}{"z": 26, "y": 25}},
+			expectedDiff: SettingsDiff{
+				Added: []SettingChange{
+					{Path: "a", NewValue: 1},
+					{Path: "b", NewValue: map[string]interface{}{"z": 26, "y": 25}},
+					{Path: "c", NewValue: 3},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := Diff(test.old, test.new)
+			assert.Equal(t, test.expectedDiff, diff)
+			assert.Equal(t, test.expectedDiff.IsEmpty(), diff.IsEmpty())
+		})
+	}
+}
+
+func TestSettingsDiff_String(t *testing.T) {
+	diff := SettingsDiff{
+		Added:   []SettingChange{{Path: "honeypot.max_recording_seconds", NewValue: 600}},
+		Removed: []SettingChange{{Path: "honeypot.log_stream", OldValue: "sip"}},
+		Changed: []SettingChange{{Path: "honeypot.answer_odds", OldValue: 100, NewValue: 50}},
+	}
+
+	assert.Equal(t, "+ honeypot.max_recording_seconds: 600\n- honeypot.log_stream: sip\n~ honeypot.answer_odds: 100 => 50", diff.String())
+}
@@ -0,0 +1,196 @@
+package process_settings
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// DirectorySource returns a Source that reads an uncombined settings directory, such as a
+// checkout of the settings repository, the way combine_process_settings does: every .yml
+// file in the directory tree holds the target and settings of one settings file, and
+// the files are combined in alphabetical order by their path relative to the directory,
+// which becomes their filename. The combined settings have a meta.version of 0.
+// Files and directories whose names start with a dot are skipped, and like
+// combine_process_settings, files ending in .yaml are not read.
+//
+// It is a WatchableSource, and StartMonitor watches the whole directory tree for changes.
+func DirectorySource(dir string) Source {
+	return directorySource{dir}
+}
+
+type directorySource struct {
+	dir string
+}
+
+func (s directorySource) Path() string {
+	return s.dir
+}
+
+func (s directorySource) Read() ([]byte, error) {
+	settingsFiles, err := readSettingsDirectory(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(combinedSettings(settingsFiles, 0))
+}
+
+// combinedSettings returns the settings files along with the metadata in the structure of a
+// combined settings file, leaving out the keys that the settings files do not have.
+func combinedSettings(settingsFiles []SettingsFile, version int) []map[string]interface{} {
+	combined := make([]map[string]interface{}, 0, len(settingsFiles)+1)
+	for _, settingsFile := range settingsFiles {
+		entry := map[string]interface{}{"filename": settingsFile.FileName}
+		if settingsFile.Target != nil {
+			entry["target"] = settingsFile.Target
+		}
+		if settingsFile.Settings != nil {
+			entry["settings"] = settingsFile.Settings
+		}
+		combined = append(combined, entry)
+	}
+	return append(combined, map[string]interface{}{"meta": map[string]interface{}{"version": version, "END": true}})
+}
+
+// An uncombinedSettingsFile is the contents of one file in an uncombined settings directory.
+type uncombinedSettingsFile struct {
+	Target   interface{}            `yaml:"target"`
+	Settings map[string]interface{} `yaml:"settings"`
+}
+
+// readSettingsDirectory reads the settings files in the directory tree in alphabetical
+// order by path. The metadata is not included.
+func readSettingsDirectory(dir string) ([]SettingsFile, error) {
+	fileNames, err := SettingsFileNames(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	settingsFiles := make([]SettingsFile, 0, len(fileNames))
+	for _, fileName := range fileNames {
+		settingsFile, err := readUncombinedSettingsFile(dir, fileName)
+		if err != nil {
+			return nil, err
+		}
+		settingsFiles = append(settingsFiles, settingsFile)
+	}
+	return settingsFiles, nil
+}
+
+// SettingsFileNames returns the names of the settings files in an uncombined settings
+// directory in the order combine_process_settings combines them: every .yml file in the
+// directory tree, by its slash-separated path relative to dir, in alphabetical order.
+// Files and directories whose names start with a dot are skipped.
+func SettingsFileNames(dir string) ([]string, error) {
+	var fileNames []string
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath != dir && isHiddenSettingsPath(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !isSettingsFileName(entry.Name()) {
+			return nil
+		}
+
+		fileName, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		fileNames = append(fileNames, filepath.ToSlash(fileName))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(fileNames)
+	return fileNames, nil
+}
+
+func readUncombinedSettingsFile(dir, fileName string) (SettingsFile, error) {
+	contents, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(fileName)))
+	if err != nil {
+		return SettingsFile{}, err
+	}
+
+	var file uncombinedSettingsFile
+	if err := decodeYaml(contents, &file); err != nil {
+		return SettingsFile{}, &LoadError{LoadErrorParse, fmt.Errorf("The settings file %s cannot be parsed: %w", fileName, err)}
+	}
+
+	// A missing target or a target of true applies to every process.
+	settingsFile := SettingsFile{FileName: fileName, Settings: file.Settings}
+	switch target := file.Target.(type) {
+	case nil:
+	case bool:
+		if !target {
+			return SettingsFile{}, &LoadError{LoadErrorValidation, fmt.Errorf("The settings file %s has a target of false, which never matches", fileName)}
+		}
+	case map[string]interface{}:
+		settingsFile.Target = target
+	default:
+		return SettingsFile{}, &LoadError{LoadErrorValidation, fmt.Errorf("The settings file %s has a target that is not a map or true: %v", fileName, target)}
+	}
+	return settingsFile, nil
+}
+
+func isSettingsFileName(name string) bool {
+	return path.Ext(name) == ".yml"
+}
+
+func isHiddenSettingsPath(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// watchDirectoryTree adds the directory and all its subdirectories to the watcher,
+// skipping hidden directories.
+func watchDirectoryTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if filePath != dir && isHiddenSettingsPath(entry.Name()) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(filePath)
+	})
+}
+
+// isSettingsTreeChange reports whether an event in a watched settings directory tree may have
+// changed the settings. Directories created in the tree are watched as well.
+// Reloading when nothing changed is cheap, as identical combined settings are not parsed again.
+func (ps *ProcessSettings) isSettingsTreeChange(watcher *fsnotify.Watcher, event fsnotify.Event) bool {
+	if isHiddenSettingsPath(filepath.Base(event.Name)) || event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := watchDirectoryTree(watcher, event.Name); err != nil {
+				ps.logger.Error("Error watching the process settings directory",
+					"file_path", ps.FilePath,
+					"error_kind", "watch",
+					"error", err,
+				)
+				ps.observer.OnFsnotifyError(err)
+				ps.reportError(err)
+			}
+			return true
+		}
+	}
+	return isSettingsFileName(event.Name) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+}
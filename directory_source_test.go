@@ -0,0 +1,163 @@
+package process_settings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectorySource(t *testing.T) {
+	dir := t.TempDir()
+	writeUncombinedSettingsFiles(t, dir, map[string]string{
+		"telecom/log_level.yml":   "target:\n  app: telecom\nsettings:\n  logging:\n    level: debug\n",
+		"telecom-defaults.yml":    "settings:\n  logging:\n    level: info\n",
+		"honeypot.yml":            "target: true\nsettings:\n  honeypot:\n    answer_odds: 100\n",
+		"telecom/region/west.yml": "target:\n  app: telecom\n  region: west\nsettings:\n  incoming_requests: 0\n",
+		".git/config.yml":         "not: settings\n",
+		"telecom/overrides.yaml":  "target:\n  app: telecom\nsettings:\n  logging:\n    level: error\n",
+		"README.md":               "# Settings\n",
+	})
+
+	settings, err := NewProcessSettings(DirectorySource(dir), map[string]interface{}{"app": "telecom"})
+	assert.Nil(t, err)
+
+	t.Run("Combines the files in alphabetical order by path with their filenames", func(t *testing.T) {
+		assert.Equal(t, []SettingsFile{
+			{FileName: "honeypot.yml", Settings: map[string]interface{}{"honeypot": map[string]interface{}{"answer_odds": 100}}},
+			{FileName: "telecom-defaults.yml", Settings: map[string]interface{}{"logging": map[string]interface{}{"level": "info"}}},
+			{
+				FileName: "telecom/log_level.yml",
+				Target:   map[string]interface{}{"app": "telecom"},
+				Settings: map[string]interface{}{"logging": map[string]interface{}{"level": "debug"}},
+			},
+			{
+				FileName: "telecom/region/west.yml",
+				Target:   map[string]interface{}{"app": "telecom", "region": "west"},
+				Settings: map[string]interface{}{"incoming_requests": 0},
+			},
+			{Metadata: SettingsMetadata{Version: 0, End: true}},
+		}, settings.Settings())
+	})
+
+	t.Run("Applies the targeting and precedence of the combined settings", func(t *testing.T) {
+		value, err := settings.Get("logging", "level")
+		assert.Nil(t, err)
+		assert.Equal(t, "debug", value)
+		assert.Equal(t, 0, settings.Version())
+	})
+
+	t.Run("Ignores files that do not end in .yml, as combine_process_settings does", func(t *testing.T) {
+		for _, settingsFile := range settings.Settings() {
+			assert.NotEqual(t, "telecom/overrides.yaml", settingsFile.FileName)
+		}
+		value, err := settings.Get("logging", "level")
+		assert.Nil(t, err)
+		assert.NotEqual(t, "error", value)
+	})
+
+	t.Run("Lists the settings file names in the order they are combined", func(t *testing.T) {
+		fileNames, err := SettingsFileNames(dir)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"honeypot.yml", "telecom-defaults.yml", "telecom/log_level.yml", "telecom/region/west.yml"}, fileNames)
+	})
+
+	t.Run("Is watchable by its directory", func(t *testing.T) {
+		source, isWatchable := DirectorySource(dir).(WatchableSource)
+		assert.True(t, isWatchable)
+		assert.Equal(t, dir, source.Path())
+		assert.Equal(t, dir, settings.FilePath)
+	})
+}
+
+func TestDirectorySource_Errors(t *testing.T) {
+	tests := []struct {
+		name            string
+		contents        string
+		expectedKind    LoadErrorKind
+		expectedMessage string
+	}{
+		{"A file is not valid YAML", "settings: [", LoadErrorParse, "The settings file broken.yml cannot be parsed: yaml: line 1: did not find expected node content"},
+		{"A file has a target of false", "target: false\nsettings: {}\n", LoadErrorValidation, "The settings file broken.yml has a target of false, which never matches"},
+		{"A file has a target that is not a map", "target: telecom\nsettings: {}\n", LoadErrorValidation, "The settings file broken.yml has a target that is not a map or true: telecom"},
+		{"A file has no settings", "target:\n  app: telecom\n", LoadErrorValidation, "Invalid settings file at index 0: The settings file must have a filename and settings => {broken.yml map[app:telecom] map[] {0 false}}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeUncombinedSettingsFiles(t, dir, map[string]string{"broken.yml": test.contents})
+
+			_, err := NewProcessSettings(DirectorySource(dir), nil, WithLogger(nil))
+
+			var loadErr *LoadError
+			assert.True(t, errors.As(err, &loadErr))
+			assert.Equal(t, test.expectedKind, loadErr.Kind)
+			assert.Equal(t, test.expectedMessage, err.Error())
+		})
+	}
+
+	t.Run("The directory does not exist", func(t *testing.T) {
+		_, err := NewProcessSettings(DirectorySource(filepath.Join(t.TempDir(), "missing")), nil, WithLogger(nil))
+
+		var loadErr *LoadError
+		assert.True(t, errors.As(err, &loadErr))
+		assert.Equal(t, LoadErrorRead, loadErr.Kind)
+		assert.True(t, errors.Is(err, os.ErrNotExist))
+	})
+}
+
+func TestProcessSettings_MonitorDirectory(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		options []Option
+	}{
+		{"Watching", nil},
+		{"Polling", []Option{WithPolling(10 * time.Millisecond)}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeUncombinedSettingsFiles(t, dir, map[string]string{
+				"frontend/log_level.yml": "settings:\n  frontend:\n    log_level: info\n",
+			})
+			settings, err := NewProcessSettings(DirectorySource(dir), nil, append(test.options, WithLogger(nil))...)
+			assert.Nil(t, err)
+			startMonitor(t, settings)
+
+			t.Run("Reloads when a file in a subdirectory changes", func(t *testing.T) {
+				writeUncombinedSettingsFiles(t, dir, map[string]string{
+					"frontend/log_level.yml": "settings:\n  frontend:\n    log_level: warn\n",
+				})
+				assertEventuallyReloaded(t, settings, "warn")
+			})
+
+			t.Run("Reloads when a file is added in a new subdirectory", func(t *testing.T) {
+				writeUncombinedSettingsFiles(t, dir, map[string]string{
+					"frontend/overrides/debug.yml": "settings:\n  frontend:\n    log_level: debug\n",
+				})
+				assertEventuallyReloaded(t, settings, "debug")
+			})
+
+			t.Run("Reloads when a file is removed", func(t *testing.T) {
+				assert.Nil(t, os.Remove(filepath.Join(dir, "frontend", "overrides", "debug.yml")))
+				assertEventuallyReloaded(t, settings, "warn")
+			})
+		})
+	}
+}
+
+// writeUncombinedSettingsFiles atomically writes the files of an uncombined settings directory,
+// creating subdirectories as needed.
+func writeUncombinedSettingsFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for fileName, contents := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(fileName))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(filePath), 0o755))
+		assert.Nil(t, os.WriteFile(filePath+".tmp", []byte(contents), 0o644))
+		assert.Nil(t, os.Rename(filePath+".tmp", filePath))
+	}
+}
@@ -0,0 +1,80 @@
+package process_settings
+
+import "context"
+
+// A DynamicContextView reads settings from a ProcessSettings using the static context
+// of the ProcessSettings with a dynamic context merged over it.
+// It always reads the latest settings of the ProcessSettings it was created from.
+type DynamicContextView struct {
+	processSettings *ProcessSettings
+	targetEvaluator TargetEvaluator
+}
+
+type dynamicContextKey struct{}
+
+// WithContext returns a copy of ctx that carries the given dynamic targeting context,
+// to be used by GetCtx further down the call chain.
+// If ctx already carries a dynamic context, the two are merged, with the new values winning.
+func WithContext(ctx context.Context, dynamicContext map[string]interface{}) context.Context {
+	outerContext := dynamicContextFrom(ctx)
+	merged := make(map[string]interface{}, len(outerContext)+len(dynamicContext))
+	for key, value := range outerContext {
+		merged[key] = value
+	}
+	for key, value := range dynamicContext {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, dynamicContextKey{}, merged)
+}
+
+func dynamicContextFrom(ctx context.Context) map[string]interface{} {
+	dynamicContext, _ := ctx.Value(dynamicContextKey{}).(map[string]interface{})
+	return dynamicContext
+}
+
+// GetWithContext returns the value of a setting based on the static context merged
+// with the given dynamic context, where the dynamic values win.
+// The dynamic context only applies to this lookup.
+func (ps *ProcessSettings) GetWithContext(dynamicContext map[string]interface{}, settingPath ...string) (interface{}, error) {
+	targetEvaluator := ps.TargetEvaluator.withDynamicContext(dynamicContext)
+	return ps.get(&targetEvaluator, settingPath)
+}
+
+// GetCtx returns the value of a setting based on the static context merged with
+// the dynamic context attached to ctx using WithContext.
+func (ps *ProcessSettings) GetCtx(ctx context.Context, settingPath ...string) (interface{}, error) {
+	return ps.GetWithContext(dynamicContextFrom(ctx), settingPath...)
+}
+
+// WithDynamicContext returns a view of the settings that uses the static context
+// merged with the given dynamic context for every lookup.
+// The ProcessSettings itself is not modified.
+func (ps *ProcessSettings) WithDynamicContext(dynamicContext map[string]interface{}) *DynamicContextView {
+	return &DynamicContextView{
+		processSettings: ps,
+		targetEvaluator: ps.TargetEvaluator.withDynamicContext(dynamicContext),
+	}
+}
+
+// Get returns the value of a setting based on the targeting of the view.
+// If the requested setting is not found, an error is returned.
+func (v *DynamicContextView) Get(settingPath ...string) (interface{}, error) {
+	return v.processSettings.get(&v.targetEvaluator, settingPath)
+}
+
+// SafeGet returns the value of a setting based on the targeting of the view.
+// If the requested setting is not found, nil is returned without an error. Other errors,
+// such as ErrClosed after Close has been called, are returned.
+func (v *DynamicContextView) SafeGet(settingPath ...string) (interface{}, error) {
+	return ignoreNotFound(v.Get(settingPath...))
+}
+
+// Decode finds the subtree of settings at the given path based on the targeting
+// of the view and unmarshals it into target.
+func (v *DynamicContextView) Decode(settingPath []string, target interface{}) error {
+	value, err := v.Get(settingPath...)
+	if err != nil {
+		return err
+	}
+	return decodeValue(value, settingPath, target)
+}
@@ -0,0 +1,166 @@
+package process_settings
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSettings_GetWithContext(t *testing.T) {
+	staticContext := map[string]interface{}{
+		"app":    "telecom",
+		"region": "west",
+	}
+
+	tests := []struct {
+		name           string
+		dynamicContext map[string]interface{}
+		settingPath    []string
+		expectedError  string
+		expectedValue  interface{}
+	}{
+		{
+			name:          "Uses only the static context when there is no dynamic context",
+			settingPath:   []string{"log_stream", "sip"},
+			expectedError: "The setting 'log_stream.sip' was not found",
+		},
+		{
+			name:           "Matches targets that need values from the dynamic context",
+			dynamicContext: map[string]interface{}{"caller_id": "+12755554321"},
+			settingPath:    []string{"log_stream", "sip"},
+			expectedValue:  "caller_id_privacy",
+		},
+		{
+			name:           "Does not match targets when the dynamic value does not match",
+			dynamicContext: map[string]interface{}{"caller_id": "+18005550000"},
+			settingPath:    []string{"log_stream", "sip"},
+			expectedError:  "The setting 'log_stream.sip' was not found",
+		},
+		{
+			name:           "Dynamic values win over static values",
+			dynamicContext: map[string]interface{}{"app": "ccn"},
+			settingPath:    []string{"call_counts", "complete_sync_seconds"},
+			expectedValue:  60,
+		},
+		{
+			name:           "Static values that are overridden no longer match",
+			dynamicContext: map[string]interface{}{"app": "ccn"},
+			settingPath:    []string{"logging", "level"},
+			expectedError:  "The setting 'logging.level' was not found",
+		},
+	}
+
+	settings, err := NewProcessSettingsFromFile("testdata/combined_process_settings.yml", staticContext)
+	assert.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := settings.GetWithContext(test.dynamicContext, test.settingPath...)
+			viewValue, viewErr := settings.WithDynamicContext(test.dynamicContext).Get(test.settingPath...)
+			if test.expectedError == "" {
+				assert.Nil(t, err)
+				assert.Nil(t, viewErr)
+				assert.Equal(t, test.expectedValue, value)
+				assert.Equal(t, test.expectedValue, viewValue)
+			} else {
+				assert.Equal(t, test.expectedError, err.Error())
+				assert.Equal(t, test.expectedError, viewErr.Error())
+			}
+		})
+	}
+
+	t.Run("The static context is not modified", func(t *testing.T) {
+		_, _ = settings.GetWithContext(map[string]interface{}{"caller_id": "+12755554321"}, "log_stream", "sip")
+		settings.WithDynamicContext(map[string]interface{}{"app": "ccn"})
+
+		assert.Equal(t, staticContext, settings.TargetEvaluator.targetingContext)
+		_, err := settings.Get("log_stream", "sip")
+		assert.Equal(t, "The setting 'log_stream.sip' was not found", err.Error())
+	})
+}
+
+func TestDynamicContextView(t *testing.T) {
+	settings, err := NewProcessSettingsFromFile("testdata/combined_process_settings.yml", map[string]interface{}{"app": "telecom"})
+	assert.Nil(t, err)
+
+	view := settings.WithDynamicContext(map[string]interface{}{
+		"region":    "west",
+		"caller_id": "+18053334444",
+	})
+
+	t.Run("SafeGet returns nil when the setting is not found", func(t *testing.T) {
+		value, err := view.SafeGet("honeypot", "log_stream")
+
+		assert.Nil(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("Decode decodes the subtree found with the targeting of the view", func(t *testing.T) {
+		var logStream struct {
+			Sip string `yaml:"sip"`
+		}
+		err := view.Decode([]string{"log_stream"}, &logStream)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "caller_id_privacy", logStream.Sip)
+	})
+
+	t.Run("GetAs accepts a view", func(t *testing.T) {
+		value, err := GetAs[int](view, "incoming_requests")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, value)
+	})
+}
+
+func TestWithContext(t *testing.T) {
+	t.Run("Attaches the dynamic context to the context", func(t *testing.T) {
+		ctx := WithContext(context.Background(), map[string]interface{}{"caller_id": "+18053334444"})
+
+		assert.Equal(t, map[string]interface{}{"caller_id": "+18053334444"}, dynamicContextFrom(ctx))
+	})
+
+	t.Run("Merges nested dynamic contexts with the inner values winning", func(t *testing.T) {
+		outer := WithContext(context.Background(), map[string]interface{}{"caller_id": "+18053334444", "domain": "example.com"})
+		inner := WithContext(outer, map[string]interface{}{"caller_id": "+12755554321"})
+
+		assert.Equal(t, map[string]interface{}{"caller_id": "+12755554321", "domain": "example.com"}, dynamicContextFrom(inner))
+		assert.Equal(t, map[string]interface{}{"caller_id": "+18053334444", "domain": "example.com"}, dynamicContextFrom(outer))
+	})
+
+	t.Run("Returns no dynamic context when none was attached", func(t *testing.T) {
+		assert.Nil(t, dynamicContextFrom(context.Background()))
+	})
+}
+
+func TestProcessSettings_GetCtx(t *testing.T) {
+	settings, err := NewProcessSettingsFromFile("testdata/combined_process_settings.yml", map[string]interface{}{"app": "telecom"})
+	assert.Nil(t, err)
+
+	t.Run("Uses the dynamic context attached to the context", func(t *testing.T) {
+		ctx := WithContext(context.Background(), map[string]interface{}{"region": "west"})
+		ctx = WithContext(ctx, map[string]interface{}{"caller_id": "+18052223344"})
+
+		value, err := settings.GetCtx(ctx, "log_stream", "sip")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "caller_id_privacy", value)
+	})
+
+	t.Run("Uses only the static context when nothing was attached", func(t *testing.T) {
+		_, err := settings.GetCtx(context.Background(), "incoming_requests")
+
+		assert.Equal(t, "The setting 'incoming_requests' was not found", err.Error())
+	})
+
+	t.Run("The global instance uses the dynamic context attached to the context", func(t *testing.T) {
+		SetGlobalProcessSettings(settings)
+		defer SetGlobalProcessSettings(nil)
+
+		value, err := GetCtx(WithContext(context.Background(), map[string]interface{}{"region": "west"}), "incoming_requests")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, value)
+	})
+}
@@ -0,0 +1,123 @@
+package process_settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// A Format is the format of a combined settings file.
+type Format int
+
+const (
+	// FormatAuto detects the format from the extension of the file: files ending in .json
+	// are read as JSON, and anything else, including sources without a file name, as YAML.
+	FormatAuto Format = iota
+	// FormatYAML reads the combined settings file as YAML.
+	FormatYAML
+	// FormatJSON reads the combined settings file as JSON, which has the same shape as the
+	// YAML: an array of settings files with a filename, target and settings, followed by
+	// the meta entry.
+	FormatJSON
+)
+
+// settingsFormat returns the format the settings are read in, detecting it from the
+// file name of the source unless it was given with WithFormat.
+func (ps *ProcessSettings) settingsFormat() Format {
+	// A settings directory is always combined into YAML.
+	if _, isDirectory := ps.source.(directorySource); isDirectory {
+		return FormatYAML
+	}
+	if ps.format != FormatAuto {
+		return ps.format
+	}
+
+	var fileName string
+	switch source := ps.source.(type) {
+	case WatchableSource:
+		fileName = source.Path()
+	case fsSource:
+		fileName = source.name
+	}
+	if strings.EqualFold(path.Ext(fileName), ".json") {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// decodeJSON decodes JSON keeping numbers as json.Number, so they can be normalized
+// to the types the YAML decoder returns.
+func decodeJSON(contents []byte, target interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// UnmarshalJSON decodes a settings file from JSON. Numbers in the target and settings are
+// decoded to the same types as in YAML, and a target of true is treated the same as having
+// no target.
+func (s *SettingsFile) UnmarshalJSON(data []byte) error {
+	type settingsFile SettingsFile
+	var file struct {
+		settingsFile
+		Target interface{} `json:"target"`
+	}
+	if err := decodeJSON(data, &file); err != nil {
+		return err
+	}
+
+	*s = SettingsFile(file.settingsFile)
+	s.Settings = normalizeJSONNumbers(s.Settings).(map[string]interface{})
+	switch target := file.Target.(type) {
+	case nil:
+	case bool:
+		if !target {
+			return fmt.Errorf("The target of the settings file %s is false, which never matches", s.FileName)
+		}
+	case map[string]interface{}:
+		s.Target = normalizeJSONNumbers(target).(map[string]interface{})
+	default:
+		return fmt.Errorf("The target of the settings file %s is not a map or true: %v", s.FileName, target)
+	}
+	return nil
+}
+
+// normalizeJSONNumbers replaces the json.Numbers in a decoded JSON value with the type
+// the YAML decoder uses for the same number: an int when it fits, then a uint64, and
+// otherwise a float64.
+func normalizeJSONNumbers(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		if intValue, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			if intValue == int64(int(intValue)) {
+				return int(intValue)
+			}
+			return intValue
+		}
+		if uintValue, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+			return uintValue
+		}
+		floatValue, _ := value.Float64()
+		return floatValue
+	case map[string]interface{}:
+		if value == nil {
+			return value
+		}
+		normalized := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			normalized[key] = normalizeJSONNumbers(item)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(value))
+		for i, item := range value {
+			normalized[i] = normalizeJSONNumbers(item)
+		}
+		return normalized
+	default:
+		return value
+	}
+}
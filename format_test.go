@@ -0,0 +1,142 @@
+package process_settings
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSettings_JSONFormat(t *testing.T) {
+	yamlSettings, err := NewProcessSettingsFromFile("testdata/combined_process_settings.yml", nil, WithLogger(nil))
+	assert.Nil(t, err)
+	contents, err := os.ReadFile("testdata/combined_process_settings.json")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name    string
+		source  Source
+		options []Option
+	}{
+		{"Detects JSON from the extension of a file", FileSource("testdata/combined_process_settings.json"), nil},
+		{"Detects JSON from the extension of a file in an fs.FS", FSSource(fstest.MapFS{"settings.JSON": {Data: contents}}, "settings.JSON"), nil},
+		{"Reads JSON when it is given as the format", BytesSource(contents), []Option{WithFormat(FormatJSON)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings, err := NewProcessSettings(test.source, map[string]interface{}{"app": "telecom"}, append(test.options, WithLogger(nil))...)
+			assert.Nil(t, err)
+
+			assert.Equal(t, yamlSettings.Settings(), settings.Settings())
+			value, err := settings.Get("logging", "level")
+			assert.Nil(t, err)
+			assert.Equal(t, "debug", value)
+			assert.Equal(t, 17, settings.Version())
+		})
+	}
+
+	t.Run("Reads YAML when it is given as the format", func(t *testing.T) {
+		yamlContents, err := os.ReadFile("testdata/combined_process_settings.yml")
+		assert.Nil(t, err)
+		filePath := filepath.Join(t.TempDir(), "combined_process_settings.json")
+		assert.Nil(t, os.WriteFile(filePath, yamlContents, 0o644))
+
+		settings, err := NewProcessSettingsFromFile(filePath, nil, WithFormat(FormatYAML), WithLogger(nil))
+
+		assert.Nil(t, err)
+		assert.Equal(t, yamlSettings.Settings(), settings.Settings())
+	})
+
+	t.Run("Reloads the JSON file", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "combined_process_settings.json")
+		assert.Nil(t, os.WriteFile(filePath, contents, 0o644))
+		settings, err := NewProcessSettingsFromFile(filePath, nil, WithLogger(nil))
+		assert.Nil(t, err)
+
+		assert.Nil(t, os.WriteFile(filePath, []byte(`[{"filename": "honeypot.yml", "settings": {"honeypot": {"answer_odds": 50}}}, {"meta": {"version": 18, "END": true}}]`), 0o644))
+		result, err := settings.Reload()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 18, result.Version)
+		value, _ := settings.Get("honeypot", "answer_odds")
+		assert.Equal(t, 50, value)
+	})
+
+	t.Run("Returns a parse error for invalid JSON", func(t *testing.T) {
+		_, err := NewProcessSettings(BytesSource([]byte(`[{"filename": }]`)), nil, WithFormat(FormatJSON), WithLogger(nil))
+
+		assert.Equal(t, string(LoadErrorParse), errorKind(err))
+		assert.Equal(t, "invalid character '}' looking for beginning of value", err.Error())
+	})
+
+	t.Run("Returns the validation error when the END metadata is missing", func(t *testing.T) {
+		_, err := NewProcessSettings(BytesSource([]byte(`[]`)), nil, WithFormat(FormatJSON), WithLogger(nil))
+
+		assert.Equal(t, string(LoadErrorValidation), errorKind(err))
+		assert.Equal(t, "The settings file does not have the END metadata", err.Error())
+	})
+}
+
+func TestSettingsFileUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		contents     string
+		expectedFile SettingsFile
+	}{
+		{
+			name:     "Decodes numbers to the same types as YAML",
+			contents: `{"filename": "numbers.yml", "target": {"port": 5060}, "settings": {"int": 42, "negative": -7, "float": 1.5, "whole_float": 3.0, "exponent": 1e3, "uint": 18446744073709551615, "list": [1, 2.5, "three"], "nested": {"zero": 0}}}`,
+			expectedFile: SettingsFile{
+				FileName: "numbers.yml",
+				Target:   map[string]interface{}{"port": 5060},
+				Settings: map[string]interface{}{
+					"int":         42,
+					"negative":    -7,
+					"float":       1.5,
+					"whole_float": 3.0,
+					"exponent":    1000.0,
+					"uint":        uint64(18446744073709551615),
+					"list":        []interface{}{1, 2.5, "three"},
+					"nested":      map[string]interface{}{"zero": 0},
+				},
+			},
+		},
+		{
+			name:     "Decodes a target of true as no target",
+			contents: `{"filename": "honeypot.yml", "target": true, "settings": {"honeypot": {"answer_odds": 100}}}`,
+			expectedFile: SettingsFile{
+				FileName: "honeypot.yml",
+				Settings: map[string]interface{}{"honeypot": map[string]interface{}{"answer_odds": 100}},
+			},
+		},
+		{
+			name:         "Decodes the metadata",
+			contents:     `{"meta": {"version": 17, "END": true}}`,
+			expectedFile: SettingsFile{Metadata: SettingsMetadata{Version: 17, End: true}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var settingsFile SettingsFile
+			err := json.Unmarshal([]byte(test.contents), &settingsFile)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.expectedFile, settingsFile)
+		})
+	}
+
+	t.Run("Returns an error for a target that is not a map or true", func(t *testing.T) {
+		var settingsFile SettingsFile
+
+		err := json.Unmarshal([]byte(`{"filename": "honeypot.yml", "target": false, "settings": {}}`), &settingsFile)
+		assert.EqualError(t, err, "The target of the settings file honeypot.yml is false, which never matches")
+
+		err = json.Unmarshal([]byte(`{"filename": "honeypot.yml", "target": "telecom", "settings": {}}`), &settingsFile)
+		assert.EqualError(t, err, "The target of the settings file honeypot.yml is not a map or true: telecom")
+	})
+}
@@ -1,6 +1,6 @@
 module github.com/Invoca/process_settings.go
 
-go 1.17
+go 1.18
 
 require (
 	github.com/stretchr/testify v1.8.2 // direct
@@ -0,0 +1,100 @@
+package process_settings
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// A backgroundTask is a goroutine that reloads the settings until its context is cancelled.
+type backgroundTask struct {
+	cancel context.CancelFunc // Cancels the context of the goroutine
+	done   chan struct{}      // Closed when the goroutine has exited
+}
+
+func startBackgroundTask(ctx context.Context, run func(ctx context.Context)) *backgroundTask {
+	ctx, cancel := context.WithCancel(ctx)
+	task := &backgroundTask{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(task.done)
+		run(ctx)
+	}()
+	return task
+}
+
+// isRunning reports whether the goroutine of the task is still running.
+// A nil task is never running.
+func (t *backgroundTask) isRunning() bool {
+	if t == nil {
+		return false
+	}
+
+	select {
+	case <-t.done:
+		// The context the task was started with was cancelled.
+		return false
+	default:
+		return true
+	}
+}
+
+// stop cancels the goroutine of the task and waits for it to exit.
+// Stopping a nil task does nothing.
+func (t *backgroundTask) stop() {
+	if t == nil {
+		return
+	}
+
+	t.cancel()
+	<-t.done
+}
+
+// Stop stops the monitor goroutine and closes its file watcher, and stops reloading
+// on signals, waiting for any WhenUpdated callbacks that are in flight to finish.
+// Both can be started again afterwards. Stop must not be called from a WhenUpdated callback.
+func (ps *ProcessSettings) Stop() {
+	ps.lifecycle.Lock()
+	defer ps.lifecycle.Unlock()
+
+	ps.stopBackgroundTasks()
+}
+
+// Close stops the monitor like Stop, closes the channels returned by Subscribe, and
+// releases the ProcessSettings. Using the ProcessSettings after it has been closed
+// returns ErrClosed. Closing a ProcessSettings more than once does nothing.
+func (ps *ProcessSettings) Close() error {
+	ps.lifecycle.Lock()
+	defer ps.lifecycle.Unlock()
+
+	if !atomic.CompareAndSwapInt32(&ps.closed, 0, 1) {
+		return nil
+	}
+	close(ps.closing)
+	ps.stopBackgroundTasks()
+	return nil
+}
+
+func (ps *ProcessSettings) stopBackgroundTasks() {
+	ps.monitorTask.stop()
+	ps.monitorTask = nil
+	ps.signalTask.stop()
+	ps.signalTask = nil
+	ps.callbacksInFlight.wait()
+}
+
+func (ps *ProcessSettings) isClosed() bool {
+	return atomic.LoadInt32(&ps.closed) == 1
+}
+
+// reloadAndReport reloads the settings from a background task, where there is no caller
+// to return an error to. Errors are passed to the error handler instead.
+func (ps *ProcessSettings) reloadAndReport() {
+	if _, err := ps.reload(); err != nil {
+		ps.reportError(err)
+	}
+}
+
+func (ps *ProcessSettings) reportError(err error) {
+	if ps.errorHandler != nil {
+		ps.errorHandler(err)
+	}
+}
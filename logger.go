@@ -0,0 +1,96 @@
+package process_settings
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// A Logger receives the log records of a ProcessSettings, such as reloads of the settings
+// file and the errors encountered while monitoring it. Each record is a message followed by
+// alternating keys and values, e.g. "file_path", "/etc/settings.yml", "version", 27.
+// A *slog.Logger satisfies this interface.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// stdLogger is the Logger used unless WithLogger is given.
+// It writes the warnings and errors with the standard log package, with the fields as
+// key=value pairs. Info records, such as successful loads, are discarded, so that only
+// problems are logged unless a Logger is given.
+type stdLogger struct{}
+
+func (stdLogger) Info(msg string, args ...any)  {}
+func (stdLogger) Warn(msg string, args ...any)  { log.Println(formatLogRecord("Warning: ", msg, args)) }
+func (stdLogger) Error(msg string, args ...any) { log.Println(formatLogRecord("", msg, args)) }
+
+func formatLogRecord(prefix, msg string, args []any) string {
+	var record strings.Builder
+	record.WriteString(prefix)
+	record.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&record, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&record, " %v", args[i])
+		}
+	}
+	return record.String()
+}
+
+// nopLogger discards all records. It is used when WithLogger is given a nil Logger.
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, args ...any)  {}
+func (nopLogger) Warn(msg string, args ...any)  {}
+func (nopLogger) Error(msg string, args ...any) {}
+
+// A LoadErrorKind describes at which step loading the settings file failed.
+type LoadErrorKind string
+
+const (
+	LoadErrorRead       LoadErrorKind = "read"       // The file could not be read
+	LoadErrorParse      LoadErrorKind = "parse"      // The file is not valid YAML of settings files
+	LoadErrorValidation LoadErrorKind = "validation" // The settings files are not valid
+)
+
+// A LoadError is returned when the settings file cannot be loaded.
+// Its message is the message of the underlying error.
+type LoadError struct {
+	Kind LoadErrorKind
+	Err  error
+}
+
+func (e *LoadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// newReadError wraps an error returned by Source.Read as a read error, unless the source
+// already returned a *LoadError of a more specific kind.
+func newReadError(err error) error {
+	var loadErr *LoadError
+	if errors.As(err, &loadErr) {
+		return err
+	}
+	return &LoadError{LoadErrorRead, err}
+}
+
+// errorKind returns the kind of error to log for err.
+func errorKind(err error) string {
+	var loadErr *LoadError
+	if errors.As(err, &loadErr) {
+		return string(loadErr.Kind)
+	}
+	var regressionErr *VersionRegressionError
+	if errors.As(err, &regressionErr) {
+		return "version_regression"
+	}
+	return "unknown"
+}
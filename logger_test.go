@@ -0,0 +1,170 @@
+package process_settings
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logRecord struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+// recordingLogger is a Logger that keeps the records it receives.
+type recordingLogger struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (l *recordingLogger) Info(msg string, args ...any)  { l.record("info", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...any)  { l.record("warn", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...any) { l.record("error", msg, args) }
+
+func (l *recordingLogger) record(level, msg string, args []any) {
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(args); i += 2 {
+		fields[args[i].(string)] = args[i+1]
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, logRecord{level, msg, fields})
+}
+
+func (l *recordingLogger) last() logRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.records[len(l.records)-1]
+}
+
+func TestProcessSettings_WithLogger(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+
+	logger := &recordingLogger{}
+	settings, err := NewProcessSettingsFromFile(filePath, nil, WithLogger(logger))
+	assert.Nil(t, err)
+
+	t.Run("Logs the initial load", func(t *testing.T) {
+		record := logger.last()
+		assert.Equal(t, "info", record.level)
+		assert.Equal(t, "Loaded the process settings file", record.msg)
+		assert.Equal(t, filePath, record.fields["file_path"])
+		assert.Equal(t, 1, record.fields["version"])
+		assert.IsType(t, time.Duration(0), record.fields["duration"])
+	})
+
+	t.Run("Logs a reload that changed the settings", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 2, "warn")
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		record := logger.last()
+		assert.Equal(t, "info", record.level)
+		assert.Equal(t, "Reloaded the process settings file", record.msg)
+		assert.Equal(t, 1, record.fields["previous_version"])
+		assert.Equal(t, 2, record.fields["version"])
+		assert.IsType(t, time.Duration(0), record.fields["duration"])
+	})
+
+	t.Run("Logs a failed reload with the kind of error", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filePath, []byte("- meta:\n    version: 3\n"), 0o644))
+		_, err := settings.Reload()
+		assert.NotNil(t, err)
+
+		record := logger.last()
+		assert.Equal(t, "error", record.level)
+		assert.Equal(t, "Error processing new version of the process settings file", record.msg)
+		assert.Equal(t, filePath, record.fields["file_path"])
+		assert.Equal(t, 2, record.fields["version"])
+		assert.Equal(t, "validation", record.fields["error_kind"])
+		assert.Equal(t, err, record.fields["error"])
+	})
+
+	t.Run("A nil logger discards the records", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 4, "info")
+		settings, err := NewProcessSettingsFromFile(filePath, nil, WithLogger(nil))
+		assert.Nil(t, err)
+		assert.Equal(t, nopLogger{}, settings.logger)
+	})
+}
+
+func TestProcessSettings_DefaultLogger(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+	settings, err := NewProcessSettingsFromFile(filePath, nil)
+	assert.Nil(t, err)
+
+	t.Run("Does not log successful loads and reloads", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 2, "warn")
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Empty(t, output.String())
+	})
+
+	t.Run("Logs a failed reload", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filePath, []byte("- meta:\n    version: 3\n"), 0o644))
+		_, err := settings.Reload()
+		assert.NotNil(t, err)
+
+		assert.Contains(t, output.String(), "Error processing new version of the process settings file file_path="+filePath)
+	})
+}
+
+func TestLoadError(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name            string
+		contents        string
+		expectedKind    LoadErrorKind
+		expectedMessage string
+	}{
+		{"The file cannot be read", "", LoadErrorRead, ""},
+		{"The file is not valid YAML", "- filename: [", LoadErrorParse, ""},
+		{"The file does not end with the END metadata", "- meta:\n    version: 1\n", LoadErrorValidation, "The settings file does not have the END metadata"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join(dir, "missing.yml")
+			if test.contents != "" {
+				filePath = filepath.Join(dir, "combined_process_settings.yml")
+				assert.Nil(t, os.WriteFile(filePath, []byte(test.contents), 0o644))
+			}
+
+			_, err := NewProcessSettingsFromFile(filePath, nil)
+
+			var loadErr *LoadError
+			assert.True(t, errors.As(err, &loadErr))
+			assert.Equal(t, test.expectedKind, loadErr.Kind)
+			assert.Equal(t, string(test.expectedKind), errorKind(err))
+			if test.expectedMessage != "" {
+				assert.Equal(t, test.expectedMessage, err.Error())
+			}
+		})
+	}
+
+	t.Run("Read errors can still be inspected", func(t *testing.T) {
+		_, err := NewProcessSettingsFromFile(filepath.Join(dir, "missing.yml"), nil)
+		assert.True(t, errors.Is(err, os.ErrNotExist))
+	})
+}
+
+func TestFormatLogRecord(t *testing.T) {
+	assert.Equal(t, "Warning: Reloaded file_path=/etc/settings.yml version=27 dangling",
+		formatLogRecord("Warning: ", "Reloaded", []any{"file_path", "/etc/settings.yml", "version", 27, "dangling"}))
+}
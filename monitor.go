@@ -0,0 +1,229 @@
+package process_settings
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// StartMonitor starts a goroutine that monitors the settings file for changes,
+// until ctx is cancelled or Stop or Close is called.
+// The directory containing the file is watched, so the file may be written in place,
+// replaced by renaming another file over it, or swapped through a symlink, as is done
+// for Kubernetes ConfigMap mounts. If the directory itself is removed or renamed, it is
+// watched again once it is back.
+// Bursts of changes within the debounce window (see WithDebounce) result in a single reload.
+// When a new version of the file fails to load, the error is logged and passed to
+// the error handler, and the previously loaded settings are kept.
+//
+// When the ProcessSettings was created with the WithPolling option, the file is polled
+// for changes instead.
+//
+// Calling StartMonitor while the monitor is already running does nothing.
+// If the ProcessSettings has been closed, ErrClosed is returned, and if the settings were
+// not loaded from a WatchableSource, ErrSourceNotWatchable is returned.
+func (ps *ProcessSettings) StartMonitor(ctx context.Context) error {
+	ps.lifecycle.Lock()
+	defer ps.lifecycle.Unlock()
+
+	if ps.isClosed() {
+		return ErrClosed
+	}
+	if ps.monitorTask.isRunning() {
+		return nil
+	}
+	if _, ok := ps.source.(WatchableSource); !ok {
+		return ErrSourceNotWatchable
+	}
+
+	if ps.pollingInterval > 0 {
+		return ps.startPolling(ctx)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	var watch settingsWatch
+	if _, isDirectory := ps.source.(directorySource); isDirectory {
+		watch = ps.settingsDirectoryWatch(watcher)
+	} else {
+		watch = ps.settingsFileWatch(watcher)
+	}
+	if err := watch.add(); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	ps.monitorTask = startBackgroundTask(ctx, func(ctx context.Context) {
+		ps.monitor(ctx, watcher, watch)
+	})
+	return nil
+}
+
+// watchRetryInterval is how often the monitor checks whether the watched directory is back
+// after it was removed or renamed. It is a variable so that tests can shorten it.
+var watchRetryInterval = time.Second
+
+// A settingsWatch describes what the monitor watches for changes to the settings.
+type settingsWatch struct {
+	dir      string                          // The directory that is watched
+	add      func() error                    // Adds dir to the watcher, also once it is back after being removed
+	isChange func(event fsnotify.Event) bool // Reports whether an event may have changed the settings
+}
+
+// settingsFileWatch watches the directory of the settings file, and reports the events
+// that changed the settings file.
+func (ps *ProcessSettings) settingsFileWatch(watcher *fsnotify.Watcher) settingsWatch {
+	var resolvedFilePath string
+
+	return settingsWatch{
+		dir: filepath.Dir(ps.FilePath),
+		add: func() error {
+			// Resolved before the watch is added, so a symlink swapped in the meantime is still noticed.
+			resolvedFilePath, _ = filepath.EvalSymlinks(ps.FilePath)
+
+			// The directory is watched rather than the file itself, so the monitor keeps working when
+			// the file is replaced by renaming another file over it, or by swapping a symlink.
+			return watcher.Add(filepath.Dir(ps.FilePath))
+		},
+		isChange: func(event fsnotify.Event) bool {
+			return isSettingsFileChange(ps.FilePath, &resolvedFilePath, event)
+		},
+	}
+}
+
+// settingsDirectoryWatch watches the settings directory tree, and reports the events that
+// may have changed the settings.
+func (ps *ProcessSettings) settingsDirectoryWatch(watcher *fsnotify.Watcher) settingsWatch {
+	return settingsWatch{
+		dir: ps.FilePath,
+		add: func() error {
+			return watchDirectoryTree(watcher, ps.FilePath)
+		},
+		isChange: func(event fsnotify.Event) bool {
+			return ps.isSettingsTreeChange(watcher, event)
+		},
+	}
+}
+
+// monitor reloads the settings when the watch reports that an event of the watcher changed them.
+func (ps *ProcessSettings) monitor(ctx context.Context, watcher *fsnotify.Watcher, watch settingsWatch) {
+	defer watcher.Close()
+
+	// The timer of the pending debounced reload, if any.
+	var debounceTimer *time.Timer
+	var debounced <-chan time.Time
+	// The timer of the next attempt to watch the directory again after it was removed, if any.
+	var retryTimer *time.Timer
+	var retryWatch <-chan time.Time
+	defer func() {
+		if debounceTimer != nil {
+			debounceTimer.Stop()
+		}
+		if retryTimer != nil {
+			retryTimer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-debounced:
+			debounced = nil
+			ps.reloadAndReport()
+		case <-retryWatch:
+			if err := watch.add(); err != nil {
+				retryTimer = time.NewTimer(watchRetryInterval)
+				retryWatch = retryTimer.C
+				continue
+			}
+			retryWatch = nil
+			ps.logger.Info("Watching the process settings directory again", "file_path", ps.FilePath)
+
+			// Changes made while the directory was not watched were missed, so the settings are
+			// reloaded if they are there again.
+			if _, err := os.Stat(ps.FilePath); err == nil {
+				ps.reloadAndReport()
+			}
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if isWatchedDirectoryGone(watch.dir, event) {
+				// The watch went away with the directory, and cannot be added again until the
+				// directory is back, so check for it periodically. The previously loaded settings
+				// are kept in the meantime.
+				ps.logger.Warn("The process settings directory was removed, waiting for it to come back",
+					"file_path", ps.FilePath,
+				)
+				if retryTimer != nil {
+					retryTimer.Stop()
+				}
+				retryTimer = time.NewTimer(watchRetryInterval)
+				retryWatch = retryTimer.C
+				continue
+			}
+			if !watch.isChange(event) {
+				continue
+			}
+			if ps.debounce <= 0 {
+				ps.reloadAndReport()
+				continue
+			}
+
+			// Every change restarts the debounce window, so the reload happens once the burst is over.
+			if debounceTimer != nil {
+				debounceTimer.Stop()
+			}
+			debounceTimer = time.NewTimer(ps.debounce)
+			debounced = debounceTimer.C
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			ps.logger.Error("Error reported from fsnotify",
+				"file_path", ps.FilePath,
+				"error_kind", "fsnotify",
+				"error", err,
+			)
+			ps.observer.OnFsnotifyError(err)
+			ps.reportError(err)
+		}
+	}
+}
+
+// isSettingsFileChange reports whether an event in the watched directory changed the
+// contents of the settings file. That is the case when the file itself (or the file its
+// symlink points to) is written or created, or when the file now resolves to a different
+// path because a symlink along the way was swapped. resolvedFilePath holds the path the
+// file resolved to when it was last checked, and is updated.
+func isSettingsFileChange(filePath string, resolvedFilePath *string, event fsnotify.Event) bool {
+	currentResolvedFilePath, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		// The file is missing, e.g. in between being removed and recreated.
+		// The previously loaded settings are kept until it comes back.
+		return false
+	}
+
+	if currentResolvedFilePath != *resolvedFilePath {
+		*resolvedFilePath = currentResolvedFilePath
+		return true
+	}
+
+	eventPath := filepath.Clean(event.Name)
+	if eventPath != filepath.Clean(filePath) && eventPath != currentResolvedFilePath {
+		return false
+	}
+	return event.Has(fsnotify.Write) || event.Has(fsnotify.Create)
+}
+
+// isWatchedDirectoryGone reports whether an event removed or renamed the watched directory itself.
+func isWatchedDirectoryGone(dir string, event fsnotify.Event) bool {
+	return filepath.Clean(event.Name) == filepath.Clean(dir) && (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename))
+}
@@ -0,0 +1,394 @@
+package process_settings
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSettings_ConcurrentReadsDuringReload(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+
+	settings, err := NewProcessSettingsFromFile(filePath, map[string]interface{}{"app": "frontend"})
+	assert.Nil(t, err)
+	startMonitor(t, settings)
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					value, err := settings.Get("frontend", "log_level")
+					assert.Nil(t, err)
+					assert.Contains(t, []interface{}{"info", "warn"}, value)
+				}
+			}
+		}()
+	}
+
+	// The file is overwritten in place with a single write, so the monitor never sees it truncated.
+	file, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+	assert.Nil(t, err)
+	_, err = file.Write(settingsFileContents(2, "warn"))
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	assert.Eventually(t, func() bool {
+		value, _ := settings.Get("frontend", "log_level")
+		return value == "warn"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(done)
+	readers.Wait()
+}
+
+func TestProcessSettings_KeepsLastKnownGoodSettings(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+
+	reloadErrors := make(chan error, 10)
+	settings, err := NewProcessSettingsFromFile(filePath, nil, WithErrorHandler(func(err error) {
+		reloadErrors <- err
+	}))
+	assert.Nil(t, err)
+	startMonitor(t, settings)
+
+	t.Run("Keeps the previous settings when the new file is invalid", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filePath, []byte("---\n- filename: frontend.yml\n  settings: [\n"), 0o644))
+
+		select {
+		case err := <-reloadErrors:
+			assert.Error(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("The error handler was not called")
+		}
+
+		value, err := settings.Get("frontend", "log_level")
+		assert.Nil(t, err)
+		assert.Equal(t, "info", value)
+	})
+
+	t.Run("Keeps the previous settings when the new file is missing the END metadata", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filePath, []byte("--- []\n"), 0o644))
+
+		assert.Eventually(t, func() bool {
+			return len(reloadErrors) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		value, err := settings.Get("frontend", "log_level")
+		assert.Nil(t, err)
+		assert.Equal(t, "info", value)
+	})
+
+	t.Run("Loads the next valid version of the file", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 2, "debug")
+
+		assert.Eventually(t, func() bool {
+			value, _ := settings.Get("frontend", "log_level")
+			return value == "debug"
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestProcessSettings_MonitorFileReplacement(t *testing.T) {
+	t.Run("Reloads the file when another file is renamed over it", func(t *testing.T) {
+		directory := t.TempDir()
+		filePath := filepath.Join(directory, "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+
+		settings, err := NewProcessSettingsFromFile(filePath, nil)
+		assert.Nil(t, err)
+		startMonitor(t, settings)
+
+		for version, logLevel := range []string{"debug", "warn"} {
+			replaceSettingsFile(t, filePath, version+2, logLevel)
+			assertEventuallyReloaded(t, settings, logLevel)
+		}
+	})
+
+	t.Run("Reloads the file when it is removed and created again", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+
+		settings, err := NewProcessSettingsFromFile(filePath, nil)
+		assert.Nil(t, err)
+		startMonitor(t, settings)
+
+		assert.Nil(t, os.Remove(filePath))
+		writeSettingsFile(t, filePath, 2, "debug")
+
+		assertEventuallyReloaded(t, settings, "debug")
+	})
+
+	t.Run("Reloads the file when its directory is removed and created again", func(t *testing.T) {
+		shortenWatchRetryInterval(t)
+		directory := filepath.Join(t.TempDir(), "process_settings")
+		assert.Nil(t, os.Mkdir(directory, 0o755))
+		filePath := filepath.Join(directory, "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+
+		reloadErrors := make(chan error, 10)
+		settings, err := NewProcessSettingsFromFile(filePath, nil, WithLogger(nil), WithErrorHandler(func(err error) {
+			reloadErrors <- err
+		}))
+		assert.Nil(t, err)
+		startMonitor(t, settings)
+
+		assert.Nil(t, os.RemoveAll(directory))
+		time.Sleep(50 * time.Millisecond)
+		assert.Nil(t, os.Mkdir(directory, 0o755))
+		writeSettingsFile(t, filePath, 2, "debug")
+		assertEventuallyReloaded(t, settings, "debug")
+
+		// The directory is watched again, so later changes are noticed too.
+		replaceSettingsFile(t, filePath, 3, "warn")
+		assertEventuallyReloaded(t, settings, "warn")
+		assert.Empty(t, reloadErrors)
+	})
+
+	t.Run("Reloads the file when a ConfigMap style symlink is swapped", func(t *testing.T) {
+		// Kubernetes mounts ConfigMaps as <dir>/<file> -> ..data/<file>, with ..data being a
+		// symlink to a timestamped directory that is atomically replaced on every update.
+		directory := t.TempDir()
+		filePath := filepath.Join(directory, "combined_process_settings.yml")
+		swapConfigMapData(t, directory, "..2023_01_01", 1, "info")
+		assert.Nil(t, os.Symlink(filepath.Join("..data", "combined_process_settings.yml"), filePath))
+
+		settings, err := NewProcessSettingsFromFile(filePath, nil)
+		assert.Nil(t, err)
+		startMonitor(t, settings)
+
+		swapConfigMapData(t, directory, "..2023_01_02", 2, "debug")
+		assertEventuallyReloaded(t, settings, "debug")
+
+		swapConfigMapData(t, directory, "..2023_01_03", 3, "warn")
+		assertEventuallyReloaded(t, settings, "warn")
+	})
+}
+
+func TestProcessSettings_MonitorLifecycle(t *testing.T) {
+	t.Run("Starting the monitor more than once runs a single monitor", func(t *testing.T) {
+		directory := t.TempDir()
+		filePath := filepath.Join(directory, "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+		settings, _ := NewProcessSettingsFromFile(filePath, nil)
+
+		var updates int32
+		settings.WhenUpdated(func() { atomic.AddInt32(&updates, 1) }, false)
+		startMonitor(t, settings)
+		assert.Nil(t, settings.StartMonitor(context.Background()))
+
+		replaceSettingsFile(t, filePath, 2, "debug")
+		assertEventuallyReloaded(t, settings, "debug")
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&updates))
+	})
+
+	t.Run("Stop stops reloading until the monitor is started again", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+		settings, _ := NewProcessSettingsFromFile(filePath, nil)
+		startMonitor(t, settings)
+
+		settings.Stop()
+		replaceSettingsFile(t, filePath, 2, "debug")
+		time.Sleep(100 * time.Millisecond)
+
+		value, _ := settings.Get("frontend", "log_level")
+		assert.Equal(t, "info", value)
+
+		startMonitor(t, settings)
+		replaceSettingsFile(t, filePath, 3, "warn")
+		assertEventuallyReloaded(t, settings, "warn")
+	})
+
+	t.Run("Cancelling the context stops the monitor", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+		settings, _ := NewProcessSettingsFromFile(filePath, nil)
+		defer settings.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		assert.Nil(t, settings.StartMonitor(ctx))
+		monitorTask := settings.monitorTask
+		cancel()
+
+		select {
+		case <-monitorTask.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("The monitor did not stop")
+		}
+
+		assert.Nil(t, settings.StartMonitor(context.Background()))
+		replaceSettingsFile(t, filePath, 2, "debug")
+		assertEventuallyReloaded(t, settings, "debug")
+	})
+
+	t.Run("Stop waits for in-flight callbacks to finish", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+		settings, _ := NewProcessSettingsFromFile(filePath, nil)
+
+		started := make(chan struct{}, 1)
+		var finished int32
+		settings.WhenUpdated(func() {
+			started <- struct{}{}
+			time.Sleep(100 * time.Millisecond)
+			atomic.StoreInt32(&finished, 1)
+		}, false)
+		startMonitor(t, settings)
+
+		replaceSettingsFile(t, filePath, 2, "debug")
+		<-started
+		settings.Stop()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&finished))
+	})
+
+	t.Run("Using the settings after Close returns ErrClosed", func(t *testing.T) {
+		settings, _ := NewProcessSettingsFromFile("testdata/combined_process_settings.yml", nil)
+		startMonitor(t, settings)
+
+		assert.Nil(t, settings.Close())
+		assert.Nil(t, settings.Close())
+
+		_, err := settings.Get("honeypot", "answer_odds")
+		assert.Equal(t, ErrClosed, err)
+		_, err = settings.GetWithContext(map[string]interface{}{"app": "telecom"}, "logging", "level")
+		assert.Equal(t, ErrClosed, err)
+		_, err = settings.GetInt("honeypot", "answer_odds")
+		assert.Equal(t, ErrClosed, err)
+		_, err = settings.SafeGet("honeypot", "answer_odds")
+		assert.Equal(t, ErrClosed, err)
+		_, err = settings.WithDynamicContext(map[string]interface{}{"app": "telecom"}).SafeGet("logging", "level")
+		assert.Equal(t, ErrClosed, err)
+		assert.Equal(t, ErrClosed, settings.StartMonitor(context.Background()))
+	})
+}
+
+func TestProcessSettings_MonitorDebounce(t *testing.T) {
+	t.Run("Reloads once for a burst of changes", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+		settings, _ := NewProcessSettingsFromFile(filePath, nil, WithDebounce(200*time.Millisecond))
+
+		var updates int32
+		settings.WhenUpdated(func() { atomic.AddInt32(&updates, 1) }, false)
+		startMonitor(t, settings)
+
+		for version, logLevel := range []string{"debug", "warn", "error", "fatal"} {
+			writeSettingsFile(t, filePath, version+2, logLevel)
+		}
+
+		assertEventuallyReloaded(t, settings, "fatal")
+		time.Sleep(300 * time.Millisecond)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&updates))
+	})
+
+	t.Run("Reloads on every change when the debounce is disabled", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+		settings, _ := NewProcessSettingsFromFile(filePath, nil, WithDebounce(0))
+		startMonitor(t, settings)
+
+		replaceSettingsFile(t, filePath, 2, "debug")
+		assertEventuallyReloaded(t, settings, "debug")
+		replaceSettingsFile(t, filePath, 3, "warn")
+		assertEventuallyReloaded(t, settings, "warn")
+	})
+
+	t.Run("Does not call the callbacks when the file is rewritten with the same settings", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+		writeSettingsFile(t, filePath, 1, "info")
+		settings, _ := NewProcessSettingsFromFile(filePath, nil)
+
+		var updates int32
+		settings.WhenUpdated(func() { atomic.AddInt32(&updates, 1) }, false)
+		startMonitor(t, settings)
+
+		replaceSettingsFile(t, filePath, 1, "info")
+		time.Sleep(300 * time.Millisecond)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&updates))
+
+		replaceSettingsFile(t, filePath, 2, "debug")
+		assertEventuallyReloaded(t, settings, "debug")
+		// The new settings are swapped in before the callbacks are called.
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&updates) == 1 }, time.Second, 10*time.Millisecond)
+	})
+}
+
+// startMonitor starts the monitor and closes the settings when the test finishes.
+func startMonitor(t *testing.T, settings *ProcessSettings) {
+	t.Helper()
+	assert.Nil(t, settings.StartMonitor(context.Background()))
+	t.Cleanup(func() { settings.Close() })
+}
+
+// shortenWatchRetryInterval makes the monitor check often for a removed directory to come
+// back, for the duration of the test.
+func shortenWatchRetryInterval(t *testing.T) {
+	t.Helper()
+	previous := watchRetryInterval
+	watchRetryInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchRetryInterval = previous })
+}
+
+// replaceSettingsFile atomically replaces the settings file by renaming a new file over it,
+// so the monitor sees exactly one change.
+func replaceSettingsFile(t *testing.T, filePath string, version int, logLevel string) {
+	t.Helper()
+	tempFilePath := filePath + ".tmp"
+	writeSettingsFile(t, tempFilePath, version, logLevel)
+	assert.Nil(t, os.Rename(tempFilePath, filePath))
+}
+
+// swapConfigMapData writes a settings file into a new data directory and atomically
+// points the ..data symlink at it, the same way the kubelet updates ConfigMap volumes.
+func swapConfigMapData(t *testing.T, directory string, dataDirectory string, version int, logLevel string) {
+	t.Helper()
+	assert.Nil(t, os.Mkdir(filepath.Join(directory, dataDirectory), 0o755))
+	writeSettingsFile(t, filepath.Join(directory, dataDirectory, "combined_process_settings.yml"), version, logLevel)
+	assert.Nil(t, os.Symlink(dataDirectory, filepath.Join(directory, "..data_tmp")))
+	assert.Nil(t, os.Rename(filepath.Join(directory, "..data_tmp"), filepath.Join(directory, "..data")))
+}
+
+func assertEventuallyReloaded(t *testing.T, settings *ProcessSettings, logLevel string) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		value, _ := settings.Get("frontend", "log_level")
+		return value == logLevel
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// writeSettingsFile writes a combined settings file with a single frontend log level setting.
+func writeSettingsFile(t *testing.T, filePath string, version int, logLevel string) {
+	t.Helper()
+	assert.Nil(t, os.WriteFile(filePath, settingsFileContents(version, logLevel), 0o644))
+}
+
+func settingsFileContents(version int, logLevel string) []byte {
+	return []byte(fmt.Sprintf(`---
+- filename: frontend.yml
+  settings:
+    frontend:
+      log_level: %s
+- meta:
+    version: %d
+    END: true
+`, logLevel, version))
+}
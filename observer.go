@@ -0,0 +1,98 @@
+package process_settings
+
+import (
+	"sync"
+	"time"
+)
+
+// An Observer is notified of the outcome of loading the settings file and of the errors
+// encountered while monitoring it, e.g. to export metrics or alert when a host fails to
+// apply a new version of the settings. Its methods are called synchronously from the
+// goroutine doing the load, so they should return quickly.
+type Observer interface {
+	// OnLoadSuccess is called after the settings file was loaded or reloaded successfully,
+	// with the meta.version of the loaded settings and how long loading took.
+	OnLoadSuccess(version int, duration time.Duration)
+	// OnLoadFailure is called when loading or reloading the settings file failed.
+	OnLoadFailure(err error)
+	// OnCallbackPanic is called when a WhenUpdated or OnChange callback panicked.
+	OnCallbackPanic(err *CallbackPanicError)
+	// OnFsnotifyError is called when the file watcher of the monitor reports an error.
+	OnFsnotifyError(err error)
+}
+
+// nopObserver ignores all events. It is used unless WithObserver is given.
+type nopObserver struct{}
+
+func (nopObserver) OnLoadSuccess(version int, duration time.Duration) {}
+func (nopObserver) OnLoadFailure(err error)                           {}
+func (nopObserver) OnCallbackPanic(err *CallbackPanicError)           {}
+func (nopObserver) OnFsnotifyError(err error)                         {}
+
+// A Metrics is an Observer that counts the events it receives and remembers the last
+// successful load, so they can be scraped by a metrics exporter.
+// It is safe to use from multiple goroutines.
+type Metrics struct {
+	mu    sync.Mutex
+	stats MetricsSnapshot
+}
+
+// A MetricsSnapshot holds the values of a Metrics at one point in time.
+type MetricsSnapshot struct {
+	LoadSuccesses  uint64 // The number of successful loads and reloads
+	LoadFailures   uint64 // The number of failed loads and reloads
+	CallbackPanics uint64 // The number of callbacks that panicked
+	FsnotifyErrors uint64 // The number of errors reported by the file watcher
+
+	LastSuccessfulVersion int           // The meta.version of the last successful load
+	LastSuccessAt         time.Time     // When the last successful load finished; zero if there was none
+	LastSuccessDuration   time.Duration // How long the last successful load took
+	LastFailureAt         time.Time     // When the last failed load happened; zero if there was none
+	LastFailureError      error         // The error of the last failed load
+}
+
+// NewMetrics returns a Metrics with all counters at zero.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// Snapshot returns the current values of the metrics.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stats
+}
+
+func (m *Metrics) OnLoadSuccess(version int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.LoadSuccesses++
+	m.stats.LastSuccessfulVersion = version
+	m.stats.LastSuccessAt = time.Now()
+	m.stats.LastSuccessDuration = duration
+}
+
+func (m *Metrics) OnLoadFailure(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.LoadFailures++
+	m.stats.LastFailureAt = time.Now()
+	m.stats.LastFailureError = err
+}
+
+func (m *Metrics) OnCallbackPanic(err *CallbackPanicError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.CallbackPanics++
+}
+
+func (m *Metrics) OnFsnotifyError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.FsnotifyErrors++
+}
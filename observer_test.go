@@ -0,0 +1,88 @@
+package process_settings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSettings_WithObserver(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+
+	metrics := NewMetrics()
+	settings, err := NewProcessSettingsFromFile(filePath, nil, WithObserver(metrics), WithLogger(nil))
+	assert.Nil(t, err)
+
+	t.Run("Observes the initial load", func(t *testing.T) {
+		snapshot := metrics.Snapshot()
+		assert.Equal(t, uint64(1), snapshot.LoadSuccesses)
+		assert.Equal(t, 1, snapshot.LastSuccessfulVersion)
+		assert.WithinDuration(t, time.Now(), snapshot.LastSuccessAt, time.Minute)
+	})
+
+	t.Run("Observes a successful reload", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 2, "warn")
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		snapshot := metrics.Snapshot()
+		assert.Equal(t, uint64(2), snapshot.LoadSuccesses)
+		assert.Equal(t, 2, snapshot.LastSuccessfulVersion)
+	})
+
+	t.Run("Observes a failed reload and keeps the last successful version", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filePath, []byte("- meta:\n    version: 3\n"), 0o644))
+		_, reloadErr := settings.Reload()
+		assert.NotNil(t, reloadErr)
+
+		snapshot := metrics.Snapshot()
+		assert.Equal(t, uint64(1), snapshot.LoadFailures)
+		assert.Equal(t, reloadErr, snapshot.LastFailureError)
+		assert.False(t, snapshot.LastFailureAt.IsZero())
+		assert.Equal(t, 2, snapshot.LastSuccessfulVersion)
+	})
+
+	t.Run("Observes a callback that panicked", func(t *testing.T) {
+		settings.WhenUpdated(func() { panic("boom") }, false)
+		writeSettingsFile(t, filePath, 4, "debug")
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, uint64(1), metrics.Snapshot().CallbackPanics)
+	})
+
+	t.Run("Observes a failed initial load", func(t *testing.T) {
+		metrics := NewMetrics()
+		_, err := NewProcessSettingsFromFile(filepath.Join(t.TempDir(), "missing.yml"), nil, WithObserver(metrics), WithLogger(nil))
+		assert.NotNil(t, err)
+
+		snapshot := metrics.Snapshot()
+		assert.Equal(t, uint64(0), snapshot.LoadSuccesses)
+		assert.Equal(t, uint64(1), snapshot.LoadFailures)
+		assert.True(t, snapshot.LastSuccessAt.IsZero())
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.OnLoadSuccess(27, 5*time.Millisecond)
+	metrics.OnLoadFailure(errors.New("read error"))
+	metrics.OnCallbackPanic(&CallbackPanicError{Callback: "WhenUpdated callback", Value: "boom"})
+	metrics.OnFsnotifyError(errors.New("queue overflow"))
+	metrics.OnFsnotifyError(errors.New("queue overflow"))
+
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, uint64(1), snapshot.LoadSuccesses)
+	assert.Equal(t, uint64(1), snapshot.LoadFailures)
+	assert.Equal(t, uint64(1), snapshot.CallbackPanics)
+	assert.Equal(t, uint64(2), snapshot.FsnotifyErrors)
+	assert.Equal(t, 27, snapshot.LastSuccessfulVersion)
+	assert.Equal(t, 5*time.Millisecond, snapshot.LastSuccessDuration)
+	assert.Equal(t, "read error", snapshot.LastFailureError.Error())
+}
@@ -0,0 +1,99 @@
+package process_settings
+
+import "time"
+
+// An Option configures optional behavior of a ProcessSettings when it is created.
+type Option func(*ProcessSettings)
+
+// WithErrorHandler registers a function that is called with any error encountered
+// while monitoring the settings file, such as a new version of the file failing to
+// parse or validate. The previously loaded settings stay in use when that happens.
+// The function is called on the monitor goroutine.
+func WithErrorHandler(fn func(error)) Option {
+	return func(ps *ProcessSettings) {
+		ps.errorHandler = fn
+	}
+}
+
+// WithPolling makes StartMonitor poll the settings file every interval instead of
+// relying on file change notifications, for filesystems and containers where those
+// are unreliable or unavailable. The file is only reloaded when its contents changed.
+func WithPolling(interval time.Duration) Option {
+	return func(ps *ProcessSettings) {
+		ps.pollingInterval = interval
+	}
+}
+
+// DefaultDebounce is how long the monitor waits for a burst of file change events
+// to settle before reloading, unless WithDebounce is given.
+const DefaultDebounce = 100 * time.Millisecond
+
+// WithDebounce sets how long the monitor waits after a file change event for more
+// events before reloading, so that a burst of events, such as an editor writing a
+// file in several steps, results in a single reload. Zero reloads on every event.
+func WithDebounce(window time.Duration) Option {
+	return func(ps *ProcessSettings) {
+		ps.debounce = window
+	}
+}
+
+// WithCallbackTimeout limits how long the monitor waits for each WhenUpdated and OnChange
+// callback. Callbacks then run on their own goroutines, and when one does not finish within
+// the timeout a warning is logged and the next callback is called without waiting for it.
+// A callback is never called while its previous call is still running: it is skipped for
+// that reload instead, and a warning is logged. Stop and Close still wait for such callbacks
+// to finish. Zero, the default, calls the callbacks one after the other on the monitor
+// goroutine.
+func WithCallbackTimeout(timeout time.Duration) Option {
+	return func(ps *ProcessSettings) {
+		ps.callbackTimeout = timeout
+	}
+}
+
+// WithLogger sends the log records of loading and monitoring the settings file to logger
+// instead of the standard log package, which only receives the warnings and errors.
+// Info records are logged for the initial load and for reloads that changed the settings.
+// Records carry the file_path, version, error_kind and
+// duration of the reload as fields, where applicable. A *slog.Logger can be passed directly.
+// A nil logger discards the records.
+func WithLogger(logger Logger) Option {
+	return func(ps *ProcessSettings) {
+		if logger == nil {
+			ps.logger = nopLogger{}
+		} else {
+			ps.logger = logger
+		}
+	}
+}
+
+// WithObserver registers an Observer that is notified of the outcome of every load and
+// reload of the settings file, of callbacks that panic, and of file watcher errors, including
+// the initial load by NewProcessSettingsFromFile. A *Metrics can be passed to keep counters.
+func WithObserver(observer Observer) Option {
+	return func(ps *ProcessSettings) {
+		if observer == nil {
+			ps.observer = nopObserver{}
+		} else {
+			ps.observer = observer
+		}
+	}
+}
+
+// WithMonotonicVersion makes reloads refuse settings whose meta.version is lower than the
+// version that is loaded, returning a *VersionRegressionError instead, so an accidental
+// rollback of the settings file cannot quietly revert the settings. Settings with the same
+// version are still loaded.
+func WithMonotonicVersion() Option {
+	return func(ps *ProcessSettings) {
+		ps.monotonic = true
+	}
+}
+
+// WithFormat sets the format the combined settings file is read in, for sources whose
+// format cannot be detected from their file name. By default files ending in .json are
+// read as JSON, and anything else as YAML.
+func WithFormat(format Format) Option {
+	return func(ps *ProcessSettings) {
+		ps.format = format
+	}
+}
@@ -0,0 +1,87 @@
+package process_settings
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"time"
+)
+
+// A fileState is what the polling monitor remembers about the settings file between polls.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+func (ps *ProcessSettings) startPolling(ctx context.Context) error {
+	// Read before the polling starts, so a change made in the meantime is still noticed.
+	state, err := ps.readFileState()
+	if err != nil {
+		return err
+	}
+
+	ps.monitorTask = startBackgroundTask(ctx, func(ctx context.Context) {
+		ps.poll(ctx, ps.pollingInterval, state)
+	})
+	return nil
+}
+
+// readFileState stats the settings file and hashes the contents read from the source.
+func (ps *ProcessSettings) readFileState() (fileState, error) {
+	info, err := os.Stat(ps.FilePath)
+	if err != nil {
+		return fileState{}, err
+	}
+
+	contents, err := ps.source.Read()
+	if err != nil {
+		return fileState{}, err
+	}
+
+	return fileState{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		hash:    sha256.Sum256(contents),
+	}, nil
+}
+
+// poll checks the settings file every interval and reloads it when its contents changed.
+// The file is only read when its modification time or size changed, and only reloaded when
+// the hash of its contents changed, so touching the file does not trigger a reload.
+// A settings directory is read at every interval, since changes to the files in it do not
+// change the modification time of the directory itself.
+func (ps *ProcessSettings) poll(ctx context.Context, interval time.Duration, lastState fileState) {
+	_, isDirectory := ps.source.(directorySource)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(ps.FilePath)
+			if err != nil {
+				// The file is missing, e.g. in between being removed and recreated.
+				// The previously loaded settings are kept until it comes back.
+				continue
+			}
+			if !isDirectory && info.ModTime().Equal(lastState.modTime) && info.Size() == lastState.size {
+				continue
+			}
+
+			state, err := ps.readFileState()
+			if err != nil {
+				continue
+			}
+			contentsChanged := state.hash != lastState.hash
+			lastState = state
+
+			if contentsChanged {
+				ps.reloadAndReport()
+			}
+		}
+	}
+}
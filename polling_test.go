@@ -0,0 +1,66 @@
+package process_settings
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSettings_PollingMonitor(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+
+	reloadErrors := make(chan error, 10)
+	settings, err := NewProcessSettingsFromFile(filePath, nil, WithPolling(10*time.Millisecond), WithErrorHandler(func(err error) {
+		reloadErrors <- err
+	}))
+	assert.Nil(t, err)
+
+	var updates int32
+	settings.WhenUpdated(func() { atomic.AddInt32(&updates, 1) }, false)
+	startMonitor(t, settings)
+
+	// The file is replaced atomically throughout, so a poll never reads a partially written file.
+	t.Run("Reloads the file when its contents change", func(t *testing.T) {
+		replaceSettingsFile(t, filePath, 2, "debug")
+
+		assertEventuallyReloaded(t, settings, "debug")
+		// The new settings are swapped in before the callbacks are called.
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&updates) == 1 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Does not reload the file when it is touched without changing its contents", func(t *testing.T) {
+		later := time.Now().Add(time.Minute)
+		assert.Nil(t, os.Chtimes(filePath, later, later))
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&updates))
+	})
+
+	t.Run("Reports an invalid file once and keeps the previous settings", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filePath+".tmp", []byte("--- []\n"), 0o644))
+		assert.Nil(t, os.Rename(filePath+".tmp", filePath))
+
+		select {
+		case err := <-reloadErrors:
+			assert.Equal(t, "The settings file does not have the END metadata", err.Error())
+		case <-time.After(5 * time.Second):
+			t.Fatal("The error handler was not called")
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Empty(t, reloadErrors)
+		value, _ := settings.Get("frontend", "log_level")
+		assert.Equal(t, "debug", value)
+	})
+
+	t.Run("Reloads the file when it is replaced", func(t *testing.T) {
+		replaceSettingsFile(t, filePath, 3, "warn")
+
+		assertEventuallyReloaded(t, settings, "warn")
+	})
+}
@@ -2,27 +2,57 @@
 package process_settings
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"log"
-	"os"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v3"
 )
 
 // A ProcessSettings is a collection of settings files and a target evaluator
 // that can be used to get the value of a settings based on the current targeting.
+//
+// The loaded settings are kept in an immutable snapshot that is swapped atomically
+// when the settings file is reloaded, so reading settings never takes a lock and
+// is safe to do from any number of goroutines while the monitor is running.
 type ProcessSettings struct {
-	FilePath            string            // The path to the settings file that was used to create the ProcessSettings
-	Settings            *[]SettingsFile   // The settings files that make up the ProcessSettings
-	TargetEvaluator     TargetEvaluator   // The target evaluator that is used to determine which settings files are applicable
-	Monitor             *fsnotify.Watcher // The file monitor that is used to detect changes to the settings file
-	WhenUpdatedRegistry []func()          // A list of functions to call when the settings are updated
+	FilePath        string          // The path to the settings file that was used to create the ProcessSettings, if it was loaded from a path
+	TargetEvaluator TargetEvaluator // The target evaluator that is used to determine which settings files are applicable
+
+	source       Source       // Where the settings are loaded from
+	format       Format       // The format of the settings, detected from the file name unless set with WithFormat
+	snapshot     atomic.Value // The *settingsSnapshot of the currently loaded settings
+	reloading    sync.Mutex   // Serializes reloads of the settings file
+	errorHandler func(error)  // Called with errors encountered while monitoring the settings file
+	logger       Logger       // Receives the log records of loading and monitoring the settings file
+	observer     Observer     // Notified of the outcome of loading the settings file
+
+	updateCallbacks   callbackRegistry[func()]            // The callbacks registered with WhenUpdated
+	changeListeners   callbackRegistry[changeListener]    // The callbacks registered with OnChange
+	updateSubscribers callbackRegistry[*updateSubscriber] // The channels returned by Subscribe
+	callbackTimeout   time.Duration                       // When set, how long to wait for each callback before moving on
+	callbacksInFlight callbackTracker                     // Callbacks that are running on their own goroutines
+
+	pollingInterval time.Duration // When set, StartMonitor polls the settings file at this interval
+	debounce        time.Duration // How long the monitor waits for a burst of file changes to settle before reloading
+	monotonic       bool          // When set, reloads of settings with a lower meta.version are rejected
+
+	lifecycle   sync.Mutex      // Guards starting and stopping the background tasks
+	closed      int32           // Set to 1 once Close has been called
+	closing     chan struct{}   // Closed when Close is called
+	monitorTask *backgroundTask // The goroutine started by StartMonitor
+	signalTask  *backgroundTask // The goroutine started by ReloadOnSignal
 }
 
+// ErrClosed is returned when a ProcessSettings is used after Close has been called.
+var ErrClosed = errors.New("The process settings have been closed")
+
 type SettingNotFound struct {
 	SettingPath []string
 }
@@ -33,112 +63,151 @@ func (e *SettingNotFound) Error() string {
 
 // NewProcessSettingsFromFile creates a new instance of ProcessSettings by
 // loading the settings from a specified file path and using the specified
-// static context to evaluate the targeting.
-func NewProcessSettingsFromFile(filePath string, staticContext map[string]interface{}) (*ProcessSettings, error) {
-	settings, err := loadSettingsFromFile(filePath)
-	if err != nil {
-		return nil, err
-	}
+// static context to evaluate the targeting. Options can be given to configure
+// optional behavior.
+func NewProcessSettingsFromFile(filePath string, staticContext map[string]interface{}, options ...Option) (*ProcessSettings, error) {
+	return NewProcessSettings(FileSource(filePath), staticContext, options...)
+}
 
-	monitor, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, err
+// NewProcessSettings creates a new instance of ProcessSettings by loading the
+// settings from source and using the specified static context to evaluate the
+// targeting. Options can be given to configure optional behavior.
+// Reload reads the source again, and StartMonitor can only be used when the
+// source is a WatchableSource.
+func NewProcessSettings(source Source, staticContext map[string]interface{}, options ...Option) (*ProcessSettings, error) {
+	// The options are applied first, so the logger and observer also see the initial load.
+	ps := newProcessSettings(source, nil, staticContext)
+	for _, option := range options {
+		option(ps)
 	}
 
-	err = monitor.Add(filePath)
+	start := time.Now()
+	settings, contentHash, err := loadSettings(source, ps.settingsFormat())
 	if err != nil {
+		ps.logger.Error("Error loading the process settings file",
+			"file_path", ps.FilePath,
+			"error_kind", errorKind(err),
+			"error", err,
+			"duration", time.Since(start),
+		)
+		ps.observer.OnLoadFailure(err)
 		return nil, err
 	}
 
-	return &ProcessSettings{
-		FilePath:        filePath,
-		Settings:        settings,
+	snapshot := newSettingsSnapshot(settings, &ps.TargetEvaluator)
+	snapshot.contentHash = contentHash
+	ps.storeSnapshot(snapshot)
+	duration := time.Since(start)
+	ps.logger.Info("Loaded the process settings file",
+		"file_path", ps.FilePath,
+		"version", snapshot.version,
+		"duration", duration,
+	)
+	ps.observer.OnLoadSuccess(snapshot.version, duration)
+	return ps, nil
+}
+
+func newProcessSettings(source Source, settingsFiles []SettingsFile, staticContext map[string]interface{}) *ProcessSettings {
+	ps := &ProcessSettings{
 		TargetEvaluator: TargetEvaluator{staticContext},
-		Monitor:         monitor,
-	}, nil
+		source:          source,
+		logger:          stdLogger{},
+		observer:        nopObserver{},
+		debounce:        DefaultDebounce,
+		closing:         make(chan struct{}),
+	}
+	if watchable, ok := source.(WatchableSource); ok {
+		ps.FilePath = watchable.Path()
+	}
+	ps.storeSnapshot(newSettingsSnapshot(settingsFiles, &ps.TargetEvaluator))
+	return ps
+}
+
+// Settings returns the currently loaded settings files in precedence order,
+// ending with the metadata. The returned slice must not be modified.
+func (ps *ProcessSettings) Settings() []SettingsFile {
+	return ps.loadSnapshot().settingsFiles
+}
+
+// Version returns the meta.version of the currently loaded settings.
+func (ps *ProcessSettings) Version() int {
+	return ps.loadSnapshot().version
+}
+
+// LoadedAt returns when the currently loaded settings were loaded. Reloading a file
+// whose contents did not change keeps the original time.
+func (ps *ProcessSettings) LoadedAt() time.Time {
+	return ps.loadSnapshot().loadedAt
+}
+
+// EffectiveSettings returns the settings that apply to this process's static context:
+// the settings of all the matching settings files deep merged in precedence order.
+// The returned map must not be modified.
+func (ps *ProcessSettings) EffectiveSettings() map[string]interface{} {
+	return ps.loadSnapshot().effective
 }
 
 // Get returns the value of a setting based on the current targeting.
+// When the setting is a map in more than one matching settings file, the maps are
+// deep merged in precedence order, with later files winning at the leaves.
 // If the requested setting is not found, an error is returned.
+// If the ProcessSettings has been closed, ErrClosed is returned.
 func (ps *ProcessSettings) Get(settingPath ...string) (interface{}, error) {
-	var value interface{}
-
-	valueFound := false
-	for _, settingsFile := range *ps.Settings {
-		if ps.TargetEvaluator.isTargetMatch(settingsFile) {
-			if fileValue, keyExists := dig(settingsFile.Settings, settingPath...); keyExists {
-				value = fileValue
-				valueFound = true
-			}
-		}
+	if ps.isClosed() {
+		return nil, ErrClosed
 	}
+	return ps.loadSnapshot().get(settingPath)
+}
 
-	if !valueFound {
-		return nil, &SettingNotFound{settingPath}
+func (ps *ProcessSettings) get(targetEvaluator *TargetEvaluator, settingPath []string) (interface{}, error) {
+	if ps.isClosed() {
+		return nil, ErrClosed
 	}
+	return ps.loadSnapshot().getWithTargeting(targetEvaluator, settingPath)
+}
 
-	return value, nil
+func (ps *ProcessSettings) loadSnapshot() *settingsSnapshot {
+	if snapshot, ok := ps.snapshot.Load().(*settingsSnapshot); ok {
+		return snapshot
+	}
+	return emptySettingsSnapshot
+}
+
+func (ps *ProcessSettings) storeSnapshot(snapshot *settingsSnapshot) {
+	ps.snapshot.Store(snapshot)
 }
 
 // SafeGet returns the value of a setting based on the current targeting.
-// If the requested setting is not found, nil is returned.
+// If the requested setting is not found, nil is returned without an error. Other errors,
+// such as ErrClosed after Close has been called, are returned.
 func (ps *ProcessSettings) SafeGet(settingPath ...string) (interface{}, error) {
-	value, err := ps.Get(settingPath...)
-	if err != nil && errors.Is(err, &SettingNotFound{}) {
-		return nil, err
+	return ignoreNotFound(ps.Get(settingPath...))
+}
+
+// ignoreNotFound drops the error of a lookup of a setting that was not found, and passes
+// any other error through.
+func ignoreNotFound(value interface{}, err error) (interface{}, error) {
+	var notFound *SettingNotFound
+	if errors.As(err, &notFound) {
+		return nil, nil
 	}
-	return value, nil
-}
-
-// StartMonitor starts a goroutine that monitors the settings file for changes
-func (ps *ProcessSettings) StartMonitor() {
-	go func() {
-		defer ps.Monitor.Close()
-
-		for {
-			select {
-			case event, ok := <-ps.Monitor.Events:
-				if !ok {
-					return
-				}
-				if event.Has(fsnotify.Write) {
-					settings, err := loadSettingsFromFile(ps.FilePath)
-					if err != nil {
-						log.Println("Error processing new version of the process settings file:", err)
-					}
-					ps.Settings = settings
-					for _, fn := range ps.WhenUpdatedRegistry {
-						fn()
-					}
-				}
-			case err, ok := <-ps.Monitor.Errors:
-				if !ok {
-					return
-				}
-				log.Println("Error reported from fsnotify:", err)
-			}
-		}
-	}()
+	return value, err
 }
 
 // WhenUpdated registers a function to be called when the settings are updated and by default calls the function immediately.
 // Optionally false can be passed as the second argument to not call the function immediately.
-// The function returns an index that can be used to cancel the function using CancelWhenUpdated.
-func (ps *ProcessSettings) WhenUpdated(fn func(), initial_update ...bool) int {
-	ps.WhenUpdatedRegistry = append(ps.WhenUpdatedRegistry, fn)
+// The returned Subscription cancels the function. It is safe to register and cancel functions
+// while the monitor is running, including from within a callback.
+func (ps *ProcessSettings) WhenUpdated(fn func(), initial_update ...bool) *Subscription {
+	subscription := ps.updateCallbacks.register(fn)
 	if len(initial_update) == 0 || initial_update[0] == true {
 		fn()
 	}
-	return len(ps.WhenUpdatedRegistry) - 1
-}
-
-// CancelWhenUpdated cancels a function that was registered using WhenUpdated.
-func (ps *ProcessSettings) CancelWhenUpdated(index int) {
-	ps.WhenUpdatedRegistry[index] = func() {}
+	return subscription
 }
 
 func dig(settings interface{}, settingPath ...string) (interface{}, bool) {
-	if settings == nil {
+	if settings == nil || len(settingPath) == 0 {
 		return nil, false
 	}
 
@@ -162,35 +231,76 @@ func dig(settings interface{}, settingPath ...string) (interface{}, bool) {
 	}
 }
 
-func loadSettingsFromFile(filePath string) (*[]SettingsFile, error) {
+// mergeSettingValues merges the value found in a later settings file over the value
+// found so far. Maps are deep merged, any other value replaces what was there before.
+func mergeSettingValues(value, fileValue interface{}, valueFound bool) interface{} {
+	if !valueFound {
+		return fileValue
+	}
+
+	valueMap, valueIsMap := value.(map[string]interface{})
+	fileValueMap, fileValueIsMap := fileValue.(map[string]interface{})
+	if !valueIsMap || !fileValueIsMap {
+		return fileValue
+	}
+
+	return deepMerge(valueMap, fileValueMap)
+}
+
+// deepMerge returns a new map with the values of override merged over base.
+// Neither of the given maps is modified.
+func deepMerge(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = mergeSettingValues(merged[key], value, true)
+	}
+	return merged
+}
+
+// loadSettings reads and parses the settings from source, and returns them along with the
+// hash of their contents, so that reloading unchanged contents can be recognized.
+func loadSettings(source Source, format Format) ([]SettingsFile, [sha256.Size]byte, error) {
+	contents, err := source.Read()
+	if err != nil {
+		return nil, [sha256.Size]byte{}, newReadError(err)
+	}
+	settings, err := parseSettings(contents, format)
+	return settings, sha256.Sum256(contents), err
+}
+
+// parseSettings decodes and validates the contents of a combined settings file in the given format.
+// Errors are returned as a *LoadError of the parse or validation kind.
+func parseSettings(contents []byte, format Format) ([]SettingsFile, error) {
 	var settings []SettingsFile
-	err := loadYamlFile(filePath, &settings)
+	var err error
+	if format == FormatJSON {
+		err = decodeJSON(contents, &settings)
+	} else {
+		err = decodeYaml(contents, &settings)
+	}
 	if err != nil {
-		return nil, err
+		return nil, &LoadError{LoadErrorParse, err}
 	}
 
 	for i, setting := range settings {
 		valid, err := setting.isValid()
 		if !valid {
-			return nil, errors.New(fmt.Sprintf("Invalid settings file at index %d: %s => %v", i, err.Error(), setting))
+			return nil, &LoadError{LoadErrorValidation, errors.New(fmt.Sprintf("Invalid settings file at index %d: %s => %v", i, err.Error(), setting))}
 		}
 	}
 
-	if settings[len(settings)-1].Metadata.End != true {
-		return nil, errors.New("The settings file does not have the END metadata")
+	if len(settings) == 0 || settings[len(settings)-1].Metadata.End != true {
+		return nil, &LoadError{LoadErrorValidation, errors.New("The settings file does not have the END metadata")}
 	}
-	return &settings, nil
+	return settings, nil
 }
 
-func loadYamlFile(filePath string, target interface{}) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	decoder := yaml.NewDecoder(file)
-	err = decoder.Decode(target)
+func decodeYaml(contents []byte, target interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	err := decoder.Decode(target)
 	if err != nil {
 		return err
 	}
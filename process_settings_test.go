@@ -1,6 +1,9 @@
 package process_settings
 
 import (
+	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,6 +32,11 @@ func TestNewProcessSettingsFromFile(t *testing.T) {
 			fileName:      "testdata/invalid_metadata.yml",
 			expectedError: []string{"The settings file does not have the END metadata"},
 		},
+		{
+			name:          "The file doesn't have any settings",
+			fileName:      "testdata/empty_settings.yml",
+			expectedError: []string{"The settings file does not have the END metadata"},
+		},
 		{
 			name:          "The file has an invalid settings file",
 			fileName:      "testdata/invalid_settings.yml",
@@ -47,8 +55,8 @@ func TestNewProcessSettingsFromFile(t *testing.T) {
 			settings, err := NewProcessSettingsFromFile(test.fileName, nil)
 			if test.expectedError == nil {
 				assert.Nil(t, err)
-				assert.Equal(t, test.expectedSize, len(*settings.Settings))
-				assert.Equal(t, test.expectedVersion, (*settings.Settings)[test.expectedSize-1].Metadata.Version)
+				assert.Equal(t, test.expectedSize, len(settings.Settings()))
+				assert.Equal(t, test.expectedVersion, settings.Settings()[test.expectedSize-1].Metadata.Version)
 			} else {
 				assert.Error(t, err)
 				assert.Contains(t, test.expectedError, err.Error())
@@ -58,15 +66,15 @@ func TestNewProcessSettingsFromFile(t *testing.T) {
 
 	t.Run("The settings are accessible when loaded from the file", func(t *testing.T) {
 		settings, _ := NewProcessSettingsFromFile("testdata/combined_process_settings.yml", nil)
-		assert.IsType(t, &[]SettingsFile{}, settings.Settings)
-		assert.Equal(t, "telecom", (*settings.Settings)[1].Target["app"])
-		assert.Equal(t, "caller_id_privacy", (*settings.Settings)[3].Settings["log_stream"].(map[string]interface{})["sip"])
-		assert.Equal(t, "+12755554321", (*settings.Settings)[3].Target["caller_id"].([]interface{})[1])
+		assert.IsType(t, []SettingsFile{}, settings.Settings())
+		assert.Equal(t, "telecom", settings.Settings()[1].Target["app"])
+		assert.Equal(t, "caller_id_privacy", settings.Settings()[3].Settings["log_stream"].(map[string]interface{})["sip"])
+		assert.Equal(t, "+12755554321", settings.Settings()[3].Target["caller_id"].([]interface{})[1])
 	})
 }
 
 var (
-	honeypotWithoutLogStream = &[]SettingsFile{
+	honeypotWithoutLogStream = []SettingsFile{
 		{
 			FileName: "honeypot.yml",
 			Settings: map[string]interface{}{
@@ -76,7 +84,7 @@ var (
 			},
 		},
 	}
-	honeypotWithLogStream = &[]SettingsFile{
+	honeypotWithLogStream = []SettingsFile{
 		{
 			FileName: "honeypot.yml",
 			Settings: map[string]interface{}{
@@ -87,7 +95,7 @@ var (
 			},
 		},
 	}
-	honeypotWithLogStreamSetToNil = &[]SettingsFile{
+	honeypotWithLogStreamSetToNil = []SettingsFile{
 		{
 			FileName: "honeypot.yml",
 			Settings: map[string]interface{}{
@@ -98,7 +106,7 @@ var (
 			},
 		},
 	}
-	honeypotWithTarget = &[]SettingsFile{
+	honeypotWithTarget = []SettingsFile{
 		{
 			FileName: "honeypot.yml",
 			Target: map[string]interface{}{
@@ -112,7 +120,7 @@ var (
 			},
 		},
 	}
-	honeypotWithTargetedOverride = &[]SettingsFile{
+	honeypotWithTargetedOverride = []SettingsFile{
 		{
 			FileName: "honeypot.yml",
 			Settings: map[string]interface{}{
@@ -134,7 +142,46 @@ var (
 			},
 		},
 	}
-	honeypotWithSettingsArray = &[]SettingsFile{
+	honeypotWithPartialOverride = []SettingsFile{
+		{
+			FileName: "honeypot.yml",
+			Settings: map[string]interface{}{
+				"honeypot": map[string]interface{}{
+					"answer_odds": 100,
+					"log_stream": map[string]interface{}{
+						"sip":  "original",
+						"http": "original",
+					},
+				},
+			},
+		},
+		{
+			FileName: "honeypot_override.yml",
+			Target: map[string]interface{}{
+				"app": "telecom",
+			},
+			Settings: map[string]interface{}{
+				"honeypot": map[string]interface{}{
+					"log_stream": map[string]interface{}{
+						"sip": "override",
+					},
+					"max_recording_seconds": 600,
+				},
+			},
+		},
+		{
+			FileName: "honeypot_disabled.yml",
+			Target: map[string]interface{}{
+				"app": "disabled",
+			},
+			Settings: map[string]interface{}{
+				"honeypot": map[string]interface{}{
+					"log_stream": nil,
+				},
+			},
+		},
+	}
+	honeypotWithSettingsArray = []SettingsFile{
 		{
 			FileName: "honeypot.yml",
 			Settings: map[string]interface{}{
@@ -151,7 +198,7 @@ var (
 			},
 		},
 	}
-	complexHoneypotWithSettingsOnlyInTarget = &[]SettingsFile{
+	complexHoneypotWithSettingsOnlyInTarget = []SettingsFile{
 		{
 			FileName: "honeypot.yml",
 			Settings: map[string]interface{}{
@@ -185,109 +232,179 @@ var (
 
 var getAndSafeGetTests = []struct {
 	name            string
-	processSettings ProcessSettings
+	processSettings *ProcessSettings
 	settingPath     []string
 	expectedError   string
 	expectedValue   interface{}
 }{
 	{
-		name: "Returns an error when the setting is not found",
-		processSettings: ProcessSettings{
-			Settings: honeypotWithoutLogStream,
-		},
-		settingPath:   []string{"honeypot", "log_stream"},
-		expectedError: "The setting 'honeypot.log_stream' was not found",
+		name:            "Returns an error when the setting is not found",
+		processSettings: newProcessSettings(nil, honeypotWithoutLogStream, nil),
+		settingPath:     []string{"honeypot", "log_stream"},
+		expectedError:   "The setting 'honeypot.log_stream' was not found",
 	},
 	{
-		name: "Returns nil when the value is explicitly set to nil",
-		processSettings: ProcessSettings{
-			Settings: honeypotWithLogStreamSetToNil,
-		},
-		settingPath:   []string{"honeypot", "log_stream"},
-		expectedValue: nil,
+		name:            "Returns an error when no setting path is given",
+		processSettings: newProcessSettings(nil, honeypotWithLogStream, nil),
+		settingPath:     []string{},
+		expectedError:   "The setting '' was not found",
 	},
 	{
-		name: "Returns the value when the setting is found",
-		processSettings: ProcessSettings{
-			Settings: honeypotWithLogStream,
-		},
-		settingPath:   []string{"honeypot", "log_stream"},
-		expectedValue: "sip",
+		name:            "Returns nil when the value is explicitly set to nil",
+		processSettings: newProcessSettings(nil, honeypotWithLogStreamSetToNil, nil),
+		settingPath:     []string{"honeypot", "log_stream"},
+		expectedValue:   nil,
 	},
 	{
-		name: "Does not find the setting when the targeting does not match",
-		processSettings: ProcessSettings{
-			Settings: honeypotWithTarget,
-		},
-		settingPath:   []string{"honeypot", "log_stream"},
-		expectedError: "The setting 'honeypot.log_stream' was not found",
+		name:            "Returns the value when the setting is found",
+		processSettings: newProcessSettings(nil, honeypotWithLogStream, nil),
+		settingPath:     []string{"honeypot", "log_stream"},
+		expectedValue:   "sip",
+	},
+	{
+		name:            "Does not find the setting when the targeting does not match",
+		processSettings: newProcessSettings(nil, honeypotWithTarget, nil),
+		settingPath:     []string{"honeypot", "log_stream"},
+		expectedError:   "The setting 'honeypot.log_stream' was not found",
 	},
 	{
 		name: "Finds the setting when the targeting does not match",
-		processSettings: ProcessSettings{
-			Settings: honeypotWithTarget,
-			TargetEvaluator: TargetEvaluator{
-				targetingContext: map[string]interface{}{
-					"app": "telecom",
-				},
-			},
-		},
+		processSettings: newProcessSettings(nil, honeypotWithTarget, map[string]interface{}{
+			"app": "telecom",
+		}),
 		settingPath:   []string{"honeypot", "log_stream"},
 		expectedValue: "sip",
 	},
 	{
 		name: "Ignores overridden settings when the targeting does not match",
-		processSettings: ProcessSettings{
-			Settings: honeypotWithTargetedOverride,
-			TargetEvaluator: TargetEvaluator{
-				targetingContext: map[string]interface{}{
-					"app": "not telecom",
-				},
-			},
-		},
+		processSettings: newProcessSettings(nil, honeypotWithTargetedOverride, map[string]interface{}{
+			"app": "not telecom",
+		}),
 		settingPath:   []string{"honeypot", "log_stream"},
 		expectedValue: "original",
 	},
 	{
 		name: "Returns the overridden settings when the targeting matches",
-		processSettings: ProcessSettings{
-			Settings: honeypotWithTargetedOverride,
-			TargetEvaluator: TargetEvaluator{
-				targetingContext: map[string]interface{}{
-					"app": "telecom",
-				},
-			},
-		},
+		processSettings: newProcessSettings(nil, honeypotWithTargetedOverride, map[string]interface{}{
+			"app": "telecom",
+		}),
 		settingPath:   []string{"honeypot", "log_stream"},
 		expectedValue: "override",
 	},
 	{
-		name: "Returns nil when the nested setting doesn't exist due to targeting",
-		processSettings: ProcessSettings{
-			Settings: complexHoneypotWithSettingsOnlyInTarget,
-		},
-		settingPath:   []string{"honeypot", "log_stream", "telecom"},
-		expectedError: "The setting 'honeypot.log_stream.telecom' was not found",
+		name:            "Returns nil when the nested setting doesn't exist due to targeting",
+		processSettings: newProcessSettings(nil, complexHoneypotWithSettingsOnlyInTarget, nil),
+		settingPath:     []string{"honeypot", "log_stream", "telecom"},
+		expectedError:   "The setting 'honeypot.log_stream.telecom' was not found",
 	},
 	{
 		name: "Returns the setting value when the nested setting exists due to targeting",
-		processSettings: ProcessSettings{
-			Settings: complexHoneypotWithSettingsOnlyInTarget,
-			TargetEvaluator: TargetEvaluator{
-				targetingContext: map[string]interface{}{
-					"app": "telecom",
-				},
-			},
-		},
+		processSettings: newProcessSettings(nil, complexHoneypotWithSettingsOnlyInTarget, map[string]interface{}{
+			"app": "telecom",
+		}),
 		settingPath:   []string{"honeypot", "log_stream", "telecom"},
 		expectedValue: "something",
 	},
+	{
+		name: "Deep merges maps across the matching settings files",
+		processSettings: newProcessSettings(nil, honeypotWithPartialOverride, map[string]interface{}{
+			"app": "telecom",
+		}),
+		settingPath: []string{"honeypot"},
+		expectedValue: map[string]interface{}{
+			"answer_odds":           100,
+			"max_recording_seconds": 600,
+			"log_stream": map[string]interface{}{
+				"sip":  "override",
+				"http": "original",
+			},
+		},
+	},
+	{
+		name: "Deep merges nested maps across the matching settings files",
+		processSettings: newProcessSettings(nil, honeypotWithPartialOverride, map[string]interface{}{
+			"app": "telecom",
+		}),
+		settingPath: []string{"honeypot", "log_stream"},
+		expectedValue: map[string]interface{}{
+			"sip":  "override",
+			"http": "original",
+		},
+	},
+	{
+		name:            "Does not merge maps from settings files that do not match the targeting",
+		processSettings: newProcessSettings(nil, honeypotWithPartialOverride, nil),
+		settingPath:     []string{"honeypot"},
+		expectedValue: map[string]interface{}{
+			"answer_odds": 100,
+			"log_stream": map[string]interface{}{
+				"sip":  "original",
+				"http": "original",
+			},
+		},
+	},
+	{
+		name: "Replaces a map when a later settings file sets a value that is not a map",
+		processSettings: newProcessSettings(nil, honeypotWithPartialOverride, map[string]interface{}{
+			"app": "disabled",
+		}),
+		settingPath: []string{"honeypot"},
+		expectedValue: map[string]interface{}{
+			"answer_odds": 100,
+			"log_stream":  nil,
+		},
+	},
+}
+
+func TestProcessSettings_GetDoesNotModifyTheSettingsFiles(t *testing.T) {
+	settings := newProcessSettings(nil, honeypotWithPartialOverride, map[string]interface{}{
+		"app": "telecom",
+	})
+
+	_, err := settings.Get("honeypot")
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"answer_odds": 100,
+		"log_stream": map[string]interface{}{
+			"sip":  "original",
+			"http": "original",
+		},
+	}, honeypotWithPartialOverride[0].Settings["honeypot"])
+}
+
+func TestProcessSettings_GetReadsTheEffectiveSettings(t *testing.T) {
+	settings := newProcessSettings(nil, honeypotWithPartialOverride, map[string]interface{}{"app": "telecom"})
+
+	value, err := settings.Get("honeypot", "log_stream")
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"sip": "override", "http": "original"}, value)
+	// The maps of the targeted settings files are merged once when they are loaded, not on every lookup.
+	effective := settings.loadSnapshot().effective["honeypot"].(map[string]interface{})["log_stream"]
+	assert.Equal(t, reflect.ValueOf(effective).Pointer(), reflect.ValueOf(value).Pointer())
+}
+
+func TestProcessSettings_EffectiveSettings(t *testing.T) {
+	settings := newProcessSettings(nil, honeypotWithPartialOverride, map[string]interface{}{"app": "telecom"})
+
+	assert.Equal(t, map[string]interface{}{
+		"honeypot": map[string]interface{}{
+			"answer_odds":           100,
+			"max_recording_seconds": 600,
+			"log_stream": map[string]interface{}{
+				"sip":  "override",
+				"http": "original",
+			},
+		},
+	}, settings.EffectiveSettings())
 }
 
 func TestProcessSettings_SafeGet(t *testing.T) {
 	for _, test := range getAndSafeGetTests {
 		t.Run(test.name, func(t *testing.T) {
-			value, _ := test.processSettings.SafeGet(test.settingPath...)
+			value, err := test.processSettings.SafeGet(test.settingPath...)
+			assert.Nil(t, err)
 			if test.expectedError == "" {
 				assert.Equal(t, test.expectedValue, value)
 			} else {
@@ -311,3 +428,59 @@ func TestProcessSettings_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessSettings_WhenUpdated(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeHoneypotSettingsFile(t, filePath, 1, "sip", 100)
+	settings, err := NewProcessSettingsFromFile(filePath, map[string]interface{}{"app": "telecom"})
+	assert.Nil(t, err)
+
+	var calls []string
+	first := settings.WhenUpdated(func() { calls = append(calls, "first") })
+	settings.WhenUpdated(func() { calls = append(calls, "second") }, false)
+
+	t.Run("Calls the function when registered unless false is passed", func(t *testing.T) {
+		assert.Equal(t, []string{"first"}, calls)
+	})
+
+	t.Run("Calls the functions in registration order when the settings are updated", func(t *testing.T) {
+		calls = nil
+		writeHoneypotSettingsFile(t, filePath, 2, "http", 100)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, []string{"first", "second"}, calls)
+	})
+
+	t.Run("Removes the function when its subscription is cancelled", func(t *testing.T) {
+		first.Cancel()
+		first.Cancel()
+
+		calls = nil
+		writeHoneypotSettingsFile(t, filePath, 3, "sip", 100)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, []string{"second"}, calls)
+		assert.Equal(t, 1, settings.updateCallbacks.len())
+	})
+
+	t.Run("Functions can be registered and cancelled while the settings are being reloaded", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				settings.WhenUpdated(func() {}, false).Cancel()
+			}()
+		}
+		for version := 4; version < 8; version++ {
+			writeHoneypotSettingsFile(t, filePath, version, "sip", version)
+			_, err := settings.Reload()
+			assert.Nil(t, err)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, settings.updateCallbacks.len())
+	})
+}
@@ -0,0 +1,138 @@
+package process_settings
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// A ReloadResult describes the outcome of reloading the settings file.
+type ReloadResult struct {
+	PreviousVersion int  // The meta.version of the settings that were loaded before the reload
+	Version         int  // The meta.version of the settings that are loaded after the reload
+	Changed         bool // Whether the reloaded settings differ from the previously loaded ones
+
+	// The difference in effective settings for this process's static context. It can be empty
+	// even when Changed is true, if only settings targeted at other contexts changed.
+	Diff SettingsDiff
+}
+
+// Reload loads the settings from the source again and, if it is valid, swaps in the new settings.
+// When the settings changed, the OnChange and WhenUpdated callbacks are called. A callback
+// that panics is reported to the error handler and does not prevent the others from running.
+// This is what the monitor does when the file changes, but it can also be triggered
+// directly, e.g. from an admin endpoint.
+//
+// If the file fails to load, the previously loaded settings are kept and the error is
+// returned along with a result describing the settings that remain loaded.
+// If the ProcessSettings has been closed, ErrClosed is returned.
+func (ps *ProcessSettings) Reload() (ReloadResult, error) {
+	if ps.isClosed() {
+		return ReloadResult{}, ErrClosed
+	}
+	return ps.reload()
+}
+
+func (ps *ProcessSettings) reload() (ReloadResult, error) {
+	start := time.Now()
+	previous, current, err := ps.swapSnapshot()
+	result := newReloadResult(previous, current)
+	if err != nil {
+		ps.logger.Error("Error processing new version of the process settings file",
+			"file_path", ps.FilePath,
+			"version", result.Version,
+			"error_kind", errorKind(err),
+			"error", err,
+			"duration", time.Since(start),
+		)
+		ps.observer.OnLoadFailure(err)
+		return result, err
+	}
+
+	duration := time.Since(start)
+	ps.observer.OnLoadSuccess(result.Version, duration)
+	if !result.Changed {
+		return result, nil
+	}
+
+	ps.logger.Info("Reloaded the process settings file",
+		"file_path", ps.FilePath,
+		"previous_version", result.PreviousVersion,
+		"version", result.Version,
+		"duration", duration,
+	)
+
+	ps.notifySubscribers(previous, current)
+	ps.notifyChangeListeners(previous, current)
+	for _, entry := range ps.updateCallbacks.snapshot() {
+		ps.runCallback("WhenUpdated callback", entry.running, entry.callback)
+	}
+	return result, nil
+}
+
+// swapSnapshot loads the settings file and stores the new snapshot, returning the snapshot
+// it replaced and the new one. When the file fails to load or has not changed, both are the
+// previous snapshot. Concurrent reloads are serialized, so the new snapshot is always
+// compared against the snapshot it replaced.
+func (ps *ProcessSettings) swapSnapshot() (*settingsSnapshot, *settingsSnapshot, error) {
+	ps.reloading.Lock()
+	defer ps.reloading.Unlock()
+
+	previous := ps.loadSnapshot()
+
+	contents, err := ps.source.Read()
+	if err != nil {
+		return previous, previous, newReadError(err)
+	}
+
+	// Identical contents cannot produce different settings, so there is nothing to parse.
+	contentHash := sha256.Sum256(contents)
+	if contentHash == previous.contentHash {
+		return previous, previous, nil
+	}
+
+	settings, err := parseSettings(contents, ps.settingsFormat())
+	if err != nil {
+		return previous, previous, err
+	}
+
+	snapshot := newSettingsSnapshot(settings, &ps.TargetEvaluator)
+	if ps.monotonic && snapshot.version < previous.version {
+		return previous, previous, &VersionRegressionError{previous.version, snapshot.version}
+	}
+	snapshot.contentHash = contentHash
+	snapshot.diff = Diff(previous.effective, snapshot.effective)
+	ps.storeSnapshot(snapshot)
+	return previous, snapshot, nil
+}
+
+func newReloadResult(previous, current *settingsSnapshot) ReloadResult {
+	result := ReloadResult{
+		PreviousVersion: previous.version,
+		Version:         current.version,
+	}
+	if current != previous {
+		result.Changed = !reflect.DeepEqual(previous.settingsFiles, current.settingsFiles)
+		result.Diff = current.diff
+	}
+	return result
+}
+
+// A VersionRegressionError is returned when reloading settings whose meta.version is lower
+// than the version that is loaded, and WithMonotonicVersion was given.
+// The loaded settings are kept.
+type VersionRegressionError struct {
+	LoadedVersion int // The meta.version of the settings that stay loaded
+	Version       int // The lower meta.version of the rejected settings
+}
+
+func (e *VersionRegressionError) Error() string {
+	return fmt.Sprintf("The settings file version %d is lower than the loaded version %d", e.Version, e.LoadedVersion)
+}
+
+// LastDiff returns the difference in effective settings made by the most recent
+// reload that swapped in new settings. It is empty until the settings are reloaded.
+func (ps *ProcessSettings) LastDiff() SettingsDiff {
+	return ps.loadSnapshot().diff
+}
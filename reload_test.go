@@ -0,0 +1,174 @@
+package process_settings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSettings_Reload(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+	settings, err := NewProcessSettingsFromFile(filePath, nil)
+	assert.Nil(t, err)
+
+	updates := 0
+	settings.WhenUpdated(func() { updates++ }, false)
+
+	t.Run("Loads the new settings and returns the old and new versions", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 2, "debug")
+
+		result, err := settings.Reload()
+
+		assert.Nil(t, err)
+		assert.Equal(t, ReloadResult{
+			PreviousVersion: 1,
+			Version:         2,
+			Changed:         true,
+			Diff: SettingsDiff{
+				Changed: []SettingChange{{Path: "frontend.log_level", OldValue: "info", NewValue: "debug"}},
+			},
+		}, result)
+		assert.Equal(t, result.Diff, settings.LastDiff())
+		assert.Equal(t, 1, updates)
+		value, _ := settings.Get("frontend", "log_level")
+		assert.Equal(t, "debug", value)
+	})
+
+	t.Run("Reports that nothing changed when the file is the same", func(t *testing.T) {
+		result, err := settings.Reload()
+
+		assert.Nil(t, err)
+		assert.Equal(t, ReloadResult{PreviousVersion: 2, Version: 2, Changed: false}, result)
+		assert.Equal(t, 1, updates)
+		assert.False(t, settings.LastDiff().IsEmpty())
+	})
+
+	t.Run("Reports that nothing changed when only the formatting of the file changed", func(t *testing.T) {
+		contents := append([]byte("# A comment that does not change any settings\n"), settingsFileContents(2, "debug")...)
+		assert.Nil(t, os.WriteFile(filePath, contents, 0o644))
+
+		result, err := settings.Reload()
+
+		assert.Nil(t, err)
+		assert.Equal(t, ReloadResult{PreviousVersion: 2, Version: 2, Changed: false}, result)
+		assert.Equal(t, 1, updates)
+		assert.True(t, settings.LastDiff().IsEmpty())
+	})
+
+	t.Run("Returns the validation error and keeps the previous settings", func(t *testing.T) {
+		updates = 0
+		assert.Nil(t, os.WriteFile(filePath, []byte("--- []\n"), 0o644))
+
+		result, err := settings.Reload()
+
+		assert.Equal(t, "The settings file does not have the END metadata", err.Error())
+		assert.Equal(t, ReloadResult{PreviousVersion: 2, Version: 2, Changed: false}, result)
+		assert.Equal(t, 0, updates)
+		value, _ := settings.Get("frontend", "log_level")
+		assert.Equal(t, "debug", value)
+	})
+
+	t.Run("Loads settings with a lower version by default", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 1, "info")
+
+		result, err := settings.Reload()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, result.Version)
+		assert.Equal(t, 1, settings.Version())
+	})
+
+	t.Run("Returns ErrClosed after the settings have been closed", func(t *testing.T) {
+		settings.Close()
+
+		_, err := settings.Reload()
+
+		assert.Equal(t, ErrClosed, err)
+	})
+}
+
+func TestProcessSettings_VersionAndLoadedAt(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+	before := time.Now()
+	settings, err := NewProcessSettingsFromFile(filePath, nil)
+	assert.Nil(t, err)
+
+	t.Run("Return the version and load time of the loaded settings", func(t *testing.T) {
+		assert.Equal(t, 1, settings.Version())
+		assert.False(t, settings.LoadedAt().Before(before))
+		assert.False(t, settings.LoadedAt().After(time.Now()))
+	})
+
+	t.Run("Are kept when the file did not change since it was first loaded", func(t *testing.T) {
+		loadedAt := settings.LoadedAt()
+		result, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.False(t, result.Changed)
+		assert.Equal(t, loadedAt, settings.LoadedAt())
+	})
+
+	t.Run("Are updated by a reload", func(t *testing.T) {
+		loadedAt := settings.LoadedAt()
+		writeSettingsFile(t, filePath, 2, "debug")
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, 2, settings.Version())
+		assert.True(t, settings.LoadedAt().After(loadedAt))
+	})
+
+	t.Run("Are kept when the file did not change", func(t *testing.T) {
+		loadedAt := settings.LoadedAt()
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, loadedAt, settings.LoadedAt())
+	})
+}
+
+func TestProcessSettings_WithMonotonicVersion(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 5, "info")
+	settings, err := NewProcessSettingsFromFile(filePath, nil, WithMonotonicVersion())
+	assert.Nil(t, err)
+
+	t.Run("Rejects settings with a lower version and keeps the loaded settings", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 4, "debug")
+
+		result, err := settings.Reload()
+
+		var regressionErr *VersionRegressionError
+		assert.True(t, errors.As(err, &regressionErr))
+		assert.Equal(t, &VersionRegressionError{LoadedVersion: 5, Version: 4}, regressionErr)
+		assert.Equal(t, "The settings file version 4 is lower than the loaded version 5", err.Error())
+		assert.Equal(t, "version_regression", errorKind(err))
+		assert.Equal(t, ReloadResult{PreviousVersion: 5, Version: 5}, result)
+		value, _ := settings.Get("frontend", "log_level")
+		assert.Equal(t, "info", value)
+	})
+
+	t.Run("Loads settings with the same version", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 5, "warn")
+
+		result, err := settings.Reload()
+
+		assert.Nil(t, err)
+		assert.True(t, result.Changed)
+	})
+
+	t.Run("Loads settings with a higher version", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 6, "debug")
+
+		result, err := settings.Reload()
+
+		assert.Nil(t, err)
+		assert.Equal(t, 6, result.Version)
+	})
+}
@@ -1,17 +1,40 @@
 package process_settings
 
-import "errors"
+import (
+	"errors"
+
+	"gopkg.in/yaml.v3"
+)
 
 type SettingsMetadata struct {
-	Version int  `yaml:"version"`
-	End     bool `yaml:"END"`
+	Version int  `yaml:"version" json:"version"`
+	End     bool `yaml:"END" json:"END"`
 }
 
 type SettingsFile struct {
-	FileName string                 `yaml:"filename"`
-	Target   map[string]interface{} `yaml:"target"`
-	Settings map[string]interface{} `yaml:"settings"`
-	Metadata SettingsMetadata       `yaml:"meta"`
+	FileName string                 `yaml:"filename" json:"filename"`
+	Target   map[string]interface{} `yaml:"target" json:"target"`
+	Settings map[string]interface{} `yaml:"settings" json:"settings"`
+	Metadata SettingsMetadata       `yaml:"meta" json:"meta"`
+}
+
+// UnmarshalYAML decodes a settings file. A target of true, which the Ruby
+// combine_process_settings keeps for files that target every process, is
+// treated the same as having no target.
+func (s *SettingsFile) UnmarshalYAML(node *yaml.Node) error {
+	type settingsFile SettingsFile
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			var target bool
+			if node.Content[i].Value == "target" && node.Content[i+1].ShortTag() == "!!bool" && node.Content[i+1].Decode(&target) == nil && target {
+				withoutTarget := *node
+				withoutTarget.Content = append(append([]*yaml.Node{}, node.Content[:i]...), node.Content[i+2:]...)
+				node = &withoutTarget
+				break
+			}
+		}
+	}
+	return node.Decode((*settingsFile)(s))
 }
 
 func (s *SettingsFile) isValid() (bool, error) {
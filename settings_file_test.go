@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestSettingsFileValidation(t *testing.T) {
@@ -98,3 +99,46 @@ func TestSettingsFileValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestSettingsFileUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name         string
+		contents     string
+		expectedFile SettingsFile
+	}{
+		{
+			name:     "Decodes a target map",
+			contents: "filename: honeypot.yml\ntarget:\n  app: telecom\nsettings:\n  honeypot: {answer_odds: 100}\n",
+			expectedFile: SettingsFile{
+				FileName: "honeypot.yml",
+				Target:   map[string]interface{}{"app": "telecom"},
+				Settings: map[string]interface{}{"honeypot": map[string]interface{}{"answer_odds": 100}},
+			},
+		},
+		{
+			name:     "Decodes a target of true as no target",
+			contents: "filename: honeypot.yml\ntarget: true\nsettings:\n  honeypot: {answer_odds: 100}\n",
+			expectedFile: SettingsFile{
+				FileName: "honeypot.yml",
+				Settings: map[string]interface{}{"honeypot": map[string]interface{}{"answer_odds": 100}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var settingsFile SettingsFile
+			err := yaml.Unmarshal([]byte(test.contents), &settingsFile)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.expectedFile, settingsFile)
+		})
+	}
+
+	t.Run("Returns an error for a target of false", func(t *testing.T) {
+		var settingsFile SettingsFile
+		err := yaml.Unmarshal([]byte("filename: honeypot.yml\ntarget: false\nsettings: {}\n"), &settingsFile)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,74 @@
+package process_settings
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// A settingsSnapshot is the state of one successfully loaded settings file, along
+// with everything derived from it. A snapshot is never modified once it has been
+// created; a reload builds a new snapshot and swaps it in as a whole, so readers
+// always see either the old or the new state and never a mix of the two.
+type settingsSnapshot struct {
+	settingsFiles []SettingsFile         // The settings files in precedence order, ending with the metadata
+	version       int                    // The meta.version of the settings file
+	effective     map[string]interface{} // The settings of the targeted files deep merged in precedence order
+	diff          SettingsDiff           // The difference in effective settings from the snapshot this one replaced
+	loadedAt      time.Time              // When the settings were loaded
+
+	contentHash [sha256.Size]byte // The hash of the settings file contents, if the snapshot was loaded from a file
+}
+
+var emptySettingsSnapshot = &settingsSnapshot{}
+
+func newSettingsSnapshot(settingsFiles []SettingsFile, targetEvaluator *TargetEvaluator) *settingsSnapshot {
+	snapshot := &settingsSnapshot{
+		settingsFiles: settingsFiles,
+		effective:     map[string]interface{}{},
+		loadedAt:      time.Now(),
+	}
+	for _, settingsFile := range targetEvaluator.matchingSettingsFiles(settingsFiles) {
+		snapshot.effective = deepMerge(snapshot.effective, settingsFile.Settings)
+	}
+	if len(settingsFiles) > 0 {
+		snapshot.version = settingsFiles[len(settingsFiles)-1].Metadata.Version
+	}
+	return snapshot
+}
+
+// get returns the value of a setting based on the static context. It is read from the
+// effective settings, which were merged when the snapshot was created, so that lookups
+// do not merge the targeted settings files again.
+func (s *settingsSnapshot) get(settingPath []string) (interface{}, error) {
+	if value, found := dig(s.effective, settingPath...); found {
+		return value, nil
+	}
+	return nil, &SettingNotFound{settingPath}
+}
+
+// getWithTargeting returns the value of a setting based on the context of the given target
+// evaluator. The settings files matching a dynamic context are only known at lookup time, so
+// they are merged on every lookup.
+func (s *settingsSnapshot) getWithTargeting(targetEvaluator *TargetEvaluator, settingPath []string) (interface{}, error) {
+	return findSetting(targetEvaluator.matchingSettingsFiles(s.settingsFiles), settingPath)
+}
+
+// findSetting looks up the setting in each of the given settings files, merging the values
+// that are found in precedence order.
+func findSetting(settingsFiles []SettingsFile, settingPath []string) (interface{}, error) {
+	var value interface{}
+
+	valueFound := false
+	for _, settingsFile := range settingsFiles {
+		if fileValue, keyExists := dig(settingsFile.Settings, settingPath...); keyExists {
+			value = mergeSettingValues(value, fileValue, valueFound)
+			valueFound = true
+		}
+	}
+
+	if !valueFound {
+		return nil, &SettingNotFound{settingPath}
+	}
+
+	return value, nil
+}
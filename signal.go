@@ -0,0 +1,54 @@
+package process_settings
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+)
+
+// ErrNoSignals is returned by ReloadOnSignal when it is not given any signals. Without
+// signals, signal.Notify would relay every signal, and SIGINT and SIGTERM would reload the
+// settings instead of stopping the process.
+var ErrNoSignals = errors.New("At least one signal must be given to reload the process settings on")
+
+// ReloadOnSignal starts a goroutine that reloads the settings file whenever the process
+// receives one of the given signals, typically syscall.SIGHUP, until ctx is cancelled or
+// Stop or Close is called. It is an alternative to StartMonitor for filesystems where file
+// change notifications are not delivered, and goes through the same validation, error
+// handling and WhenUpdated callbacks.
+//
+// Calling ReloadOnSignal while it is already running does nothing.
+// If no signals are given, ErrNoSignals is returned, and if the ProcessSettings has been
+// closed, ErrClosed is returned.
+func (ps *ProcessSettings) ReloadOnSignal(ctx context.Context, signals ...os.Signal) error {
+	ps.lifecycle.Lock()
+	defer ps.lifecycle.Unlock()
+
+	if ps.isClosed() {
+		return ErrClosed
+	}
+	if len(signals) == 0 {
+		return ErrNoSignals
+	}
+	if ps.signalTask.isRunning() {
+		return nil
+	}
+
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+
+	ps.signalTask = startBackgroundTask(ctx, func(ctx context.Context) {
+		defer signal.Stop(received)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-received:
+				ps.reloadAndReport()
+			}
+		}
+	})
+	return nil
+}
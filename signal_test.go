@@ -0,0 +1,84 @@
+//go:build !windows
+
+package process_settings
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSettings_ReloadOnSignal(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeSettingsFile(t, filePath, 1, "info")
+	reloadErrors := make(chan error, 1)
+	settings, err := NewProcessSettingsFromFile(filePath, nil, WithErrorHandler(func(err error) {
+		reloadErrors <- err
+	}))
+	assert.Nil(t, err)
+	defer settings.Close()
+
+	updated := make(chan struct{}, 1)
+	settings.WhenUpdated(func() { updated <- struct{}{} }, false)
+
+	assert.Nil(t, settings.ReloadOnSignal(context.Background(), syscall.SIGHUP))
+	assert.Nil(t, settings.ReloadOnSignal(context.Background(), syscall.SIGHUP))
+
+	t.Run("Reloads the settings when the signal is received", func(t *testing.T) {
+		writeSettingsFile(t, filePath, 2, "debug")
+		assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+		select {
+		case <-updated:
+		case <-time.After(5 * time.Second):
+			t.Fatal("The settings were not reloaded")
+		}
+		value, _ := settings.Get("frontend", "log_level")
+		assert.Equal(t, "debug", value)
+	})
+
+	t.Run("Keeps the previous settings when the reloaded file is invalid", func(t *testing.T) {
+		assert.Nil(t, os.WriteFile(filePath, []byte("--- []\n"), 0o644))
+		assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+		select {
+		case err := <-reloadErrors:
+			assert.Equal(t, "The settings file does not have the END metadata", err.Error())
+		case <-time.After(5 * time.Second):
+			t.Fatal("The error handler was not called")
+		}
+		value, _ := settings.Get("frontend", "log_level")
+		assert.Equal(t, "debug", value)
+	})
+
+	t.Run("Stops reloading when stopped", func(t *testing.T) {
+		settings.Stop()
+
+		// Keep the signal from terminating the test process now that nothing is listening for it.
+		ignored := make(chan os.Signal, 1)
+		signal.Notify(ignored, syscall.SIGHUP)
+		defer signal.Stop(ignored)
+
+		writeSettingsFile(t, filePath, 3, "warn")
+		assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+		time.Sleep(100 * time.Millisecond)
+
+		value, _ := settings.Get("frontend", "log_level")
+		assert.Equal(t, "debug", value)
+	})
+}
+
+func TestProcessSettings_ReloadOnSignalWithoutSignals(t *testing.T) {
+	settings, err := NewProcessSettingsFromFile("testdata/combined_process_settings.yml", nil, WithLogger(nil))
+	assert.Nil(t, err)
+	defer settings.Close()
+
+	assert.Equal(t, ErrNoSignals, settings.ReloadOnSignal(context.Background()))
+	assert.False(t, settings.signalTask.isRunning())
+}
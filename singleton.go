@@ -1,9 +1,15 @@
 package process_settings
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 var instance *ProcessSettings
 
+var errGlobalProcessSettingsNotSet = errors.New("The global process settings have not been set")
+
 // SetGlobalProcessSettings sets the global process settings instance
 // to be used by the rest of the process.
 func SetGlobalProcessSettings(settings *ProcessSettings) {
@@ -15,7 +21,7 @@ func SetGlobalProcessSettings(settings *ProcessSettings) {
 // an error is returned.
 func Get(settingPath ...string) (interface{}, error) {
 	if instance == nil {
-		return nil, errors.New("The global process settings have not been set")
+		return nil, errGlobalProcessSettingsNotSet
 	}
 	return instance.Get(settingPath...)
 }
@@ -25,26 +31,136 @@ func Get(settingPath ...string) (interface{}, error) {
 // nil is returned.
 func SafeGet(settingPath ...string) (interface{}, error) {
 	if instance == nil {
-		return nil, errors.New("The global process settings have not been set")
+		return nil, errGlobalProcessSettingsNotSet
 	}
 	return instance.SafeGet(settingPath...)
 }
 
+// GetWithContext returns the value of a setting based on the static context of the global instance
+// merged with the given dynamic context.
+// If the global instance has not been set, or the requested setting is not found, an error is returned.
+func GetWithContext(dynamicContext map[string]interface{}, settingPath ...string) (interface{}, error) {
+	if instance == nil {
+		return nil, errGlobalProcessSettingsNotSet
+	}
+	return instance.GetWithContext(dynamicContext, settingPath...)
+}
+
+// GetCtx returns the value of a setting based on the static context of the global instance
+// merged with the dynamic context attached to ctx using WithContext.
+// If the global instance has not been set, or the requested setting is not found, an error is returned.
+func GetCtx(ctx context.Context, settingPath ...string) (interface{}, error) {
+	if instance == nil {
+		return nil, errGlobalProcessSettingsNotSet
+	}
+	return instance.GetCtx(ctx, settingPath...)
+}
+
+// GetString returns the value of a setting from the global instance as a string.
+// If the global instance has not been set, or the value cannot be converted, an error is returned.
+func GetString(settingPath ...string) (string, error) {
+	if instance == nil {
+		return "", errGlobalProcessSettingsNotSet
+	}
+	return instance.GetString(settingPath...)
+}
+
+// GetInt returns the value of a setting from the global instance as an int.
+// If the global instance has not been set, or the value cannot be converted, an error is returned.
+func GetInt(settingPath ...string) (int, error) {
+	if instance == nil {
+		return 0, errGlobalProcessSettingsNotSet
+	}
+	return instance.GetInt(settingPath...)
+}
+
+// GetInt64 returns the value of a setting from the global instance as an int64.
+// If the global instance has not been set, or the value cannot be converted, an error is returned.
+func GetInt64(settingPath ...string) (int64, error) {
+	if instance == nil {
+		return 0, errGlobalProcessSettingsNotSet
+	}
+	return instance.GetInt64(settingPath...)
+}
+
+// GetFloat64 returns the value of a setting from the global instance as a float64.
+// If the global instance has not been set, or the value cannot be converted, an error is returned.
+func GetFloat64(settingPath ...string) (float64, error) {
+	if instance == nil {
+		return 0, errGlobalProcessSettingsNotSet
+	}
+	return instance.GetFloat64(settingPath...)
+}
+
+// GetBool returns the value of a setting from the global instance as a bool.
+// If the global instance has not been set, or the value cannot be converted, an error is returned.
+func GetBool(settingPath ...string) (bool, error) {
+	if instance == nil {
+		return false, errGlobalProcessSettingsNotSet
+	}
+	return instance.GetBool(settingPath...)
+}
+
+// GetDuration returns the value of a setting from the global instance as a time.Duration.
+// If the global instance has not been set, or the value cannot be converted, an error is returned.
+func GetDuration(settingPath ...string) (time.Duration, error) {
+	if instance == nil {
+		return 0, errGlobalProcessSettingsNotSet
+	}
+	return instance.GetDuration(settingPath...)
+}
+
+// GetStringSlice returns the value of a setting from the global instance as a []string.
+// If the global instance has not been set, or the value cannot be converted, an error is returned.
+func GetStringSlice(settingPath ...string) ([]string, error) {
+	if instance == nil {
+		return nil, errGlobalProcessSettingsNotSet
+	}
+	return instance.GetStringSlice(settingPath...)
+}
+
+// GetStringMap returns the value of a setting from the global instance as a map[string]interface{}.
+// If the global instance has not been set, or the value cannot be converted, an error is returned.
+func GetStringMap(settingPath ...string) (map[string]interface{}, error) {
+	if instance == nil {
+		return nil, errGlobalProcessSettingsNotSet
+	}
+	return instance.GetStringMap(settingPath...)
+}
+
+// Decode finds the subtree of settings at the given path on the global instance and unmarshals it into target.
+// If the global instance has not been set, or the subtree cannot be decoded, an error is returned.
+func Decode(settingPath []string, target interface{}) error {
+	if instance == nil {
+		return errGlobalProcessSettingsNotSet
+	}
+	return instance.Decode(settingPath, target)
+}
+
 // WhenUpdated registers a function to be called when the settings are updated on the global ProcessSettings instance.
+// The returned Subscription cancels the function.
 // If the global instance has not been set, an error is returned.
-func WhenUpdated(fn func(), initial_update ...bool) (int, error) {
+func WhenUpdated(fn func(), initial_update ...bool) (*Subscription, error) {
 	if instance == nil {
-		return 0, errors.New("The global process settings have not been set")
+		return nil, errGlobalProcessSettingsNotSet
 	}
 	return instance.WhenUpdated(fn, initial_update...), nil
 }
 
-// CancelWhenUpdated cancels a function that was registered on the global ProcessSettings instance using WhenUpdated.
+// OnChange registers a function to be called when the effective value of a setting changes on the global instance.
+// If the global instance has not been set, an error is returned.
+func OnChange(settingPath []string, fn func(old, new interface{})) (*Subscription, error) {
+	if instance == nil {
+		return nil, errGlobalProcessSettingsNotSet
+	}
+	return instance.OnChange(settingPath, fn), nil
+}
+
+// Subscribe returns a channel that receives an UpdateEvent after every reload of the global instance that changed the settings.
 // If the global instance has not been set, an error is returned.
-func CancelWhenUpdated(index int) error {
+func Subscribe(ctx context.Context) (<-chan UpdateEvent, error) {
 	if instance == nil {
-		return errors.New("The global process settings have not been set")
+		return nil, errGlobalProcessSettingsNotSet
 	}
-	instance.CancelWhenUpdated(index)
-	return nil
+	return instance.Subscribe(ctx), nil
 }
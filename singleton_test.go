@@ -1,6 +1,7 @@
 package process_settings
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,4 +21,45 @@ func TestProcessSettingsSingleton(t *testing.T) {
 
 		assert.Nil(t, value)
 	})
+
+	t.Run("Typed getters return an error when the singleton instance has not been set", func(t *testing.T) {
+		SetGlobalProcessSettings(nil)
+		_, err := GetString("honeypot", "log_stream")
+
+		assert.Equal(t, "The global process settings have not been set", err.Error())
+	})
+
+	t.Run("Typed getters delegate to the singleton instance", func(t *testing.T) {
+		SetGlobalProcessSettings(typedSettings)
+		defer SetGlobalProcessSettings(nil)
+
+		value, err := GetInt("int")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 100, value)
+	})
+
+	t.Run("OnChange returns an error when the singleton instance has not been set", func(t *testing.T) {
+		SetGlobalProcessSettings(nil)
+		subscription, err := OnChange([]string{"honeypot"}, func(old, new interface{}) {})
+
+		assert.Nil(t, subscription)
+		assert.Equal(t, "The global process settings have not been set", err.Error())
+	})
+
+	t.Run("WhenUpdated returns an error when the singleton instance has not been set", func(t *testing.T) {
+		SetGlobalProcessSettings(nil)
+		subscription, err := WhenUpdated(func() {})
+
+		assert.Nil(t, subscription)
+		assert.Equal(t, "The global process settings have not been set", err.Error())
+	})
+
+	t.Run("Subscribe returns an error when the singleton instance has not been set", func(t *testing.T) {
+		SetGlobalProcessSettings(nil)
+		events, err := Subscribe(context.Background())
+
+		assert.Nil(t, events)
+		assert.Equal(t, "The global process settings have not been set", err.Error())
+	})
 }
@@ -0,0 +1,92 @@
+package process_settings
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// A Source provides the contents of a combined process settings file.
+// Read is called for the initial load and again for every reload.
+type Source interface {
+	Read() ([]byte, error)
+}
+
+// A WatchableSource is a Source backed by a path on disk, which StartMonitor can watch
+// for changes. Only sources that implement it can be monitored.
+type WatchableSource interface {
+	Source
+	Path() string
+}
+
+// ErrSourceNotWatchable is returned by StartMonitor when the settings were not loaded
+// from a WatchableSource, such as a file.
+var ErrSourceNotWatchable = errors.New("The process settings source cannot be watched for changes")
+
+// FileSource returns a Source that reads the combined settings file at the given path.
+// It is a WatchableSource, so the file can be monitored for changes.
+func FileSource(filePath string) Source {
+	return fileSource{filePath}
+}
+
+type fileSource struct {
+	filePath string
+}
+
+func (s fileSource) Read() ([]byte, error) {
+	return os.ReadFile(s.filePath)
+}
+
+func (s fileSource) Path() string {
+	return s.filePath
+}
+
+// BytesSource returns a Source with the given contents of a combined settings file.
+// Reloading it never changes the settings.
+func BytesSource(contents []byte) Source {
+	return bytesSource(contents)
+}
+
+type bytesSource []byte
+
+func (s bytesSource) Read() ([]byte, error) {
+	return s, nil
+}
+
+// ReaderSource returns a Source that reads the combined settings file from reader.
+// The reader is read in full the first time the settings are loaded, and reloading
+// returns the same contents.
+func ReaderSource(reader io.Reader) Source {
+	return &readerSource{reader: reader}
+}
+
+type readerSource struct {
+	reader   io.Reader
+	once     sync.Once
+	contents []byte
+	err      error
+}
+
+func (s *readerSource) Read() ([]byte, error) {
+	s.once.Do(func() {
+		s.contents, s.err = io.ReadAll(s.reader)
+	})
+	return s.contents, s.err
+}
+
+// FSSource returns a Source that reads the combined settings file with the given name
+// from fsys, such as an embed.FS. The file is read again on every reload.
+func FSSource(fsys fs.FS, name string) Source {
+	return fsSource{fsys, name}
+}
+
+type fsSource struct {
+	fsys fs.FS
+	name string
+}
+
+func (s fsSource) Read() ([]byte, error) {
+	return fs.ReadFile(s.fsys, s.name)
+}
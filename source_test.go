@@ -0,0 +1,87 @@
+package process_settings
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//go:embed testdata/combined_process_settings.yml
+var embeddedSettings embed.FS
+
+func TestNewProcessSettings(t *testing.T) {
+	contents, err := os.ReadFile("testdata/combined_process_settings.yml")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name   string
+		source Source
+	}{
+		{"From a file", FileSource("testdata/combined_process_settings.yml")},
+		{"From bytes", BytesSource(contents)},
+		{"From a reader", ReaderSource(bytes.NewReader(contents))},
+		{"From an fs.FS", FSSource(fstest.MapFS{"settings.yml": {Data: contents}}, "settings.yml")},
+		{"From an embed.FS", FSSource(embeddedSettings, "testdata/combined_process_settings.yml")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings, err := NewProcessSettings(test.source, map[string]interface{}{"app": "telecom"})
+			assert.Nil(t, err)
+
+			value, err := settings.Get("logging", "level")
+			assert.Nil(t, err)
+			assert.Equal(t, "debug", value)
+			assert.Equal(t, 17, settings.Version())
+
+			result, err := settings.Reload()
+			assert.Nil(t, err)
+			assert.False(t, result.Changed)
+		})
+	}
+
+	t.Run("Returns a read error when the source cannot be read", func(t *testing.T) {
+		_, err := NewProcessSettings(FSSource(fstest.MapFS{}, "settings.yml"), nil)
+
+		var loadErr *LoadError
+		assert.True(t, errors.As(err, &loadErr))
+		assert.Equal(t, LoadErrorRead, loadErr.Kind)
+		assert.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+
+	t.Run("Returns the validation error when the source is invalid", func(t *testing.T) {
+		_, err := NewProcessSettings(BytesSource([]byte("--- []\n")), nil)
+
+		assert.Equal(t, "The settings file does not have the END metadata", err.Error())
+	})
+}
+
+func TestProcessSettings_StartMonitorWithSource(t *testing.T) {
+	t.Run("Only a file source can be watched", func(t *testing.T) {
+		source, isWatchable := FileSource("testdata/combined_process_settings.yml").(WatchableSource)
+
+		assert.True(t, isWatchable)
+		assert.Equal(t, "testdata/combined_process_settings.yml", source.Path())
+		for _, source := range []Source{BytesSource(nil), ReaderSource(bytes.NewReader(nil)), FSSource(embeddedSettings, "")} {
+			_, isWatchable := source.(WatchableSource)
+			assert.False(t, isWatchable)
+		}
+	})
+
+	t.Run("Returns ErrSourceNotWatchable for a source that cannot be watched", func(t *testing.T) {
+		contents, err := os.ReadFile("testdata/combined_process_settings.yml")
+		assert.Nil(t, err)
+		settings, err := NewProcessSettings(BytesSource(contents), nil)
+		assert.Nil(t, err)
+
+		assert.Equal(t, ErrSourceNotWatchable, settings.StartMonitor(context.Background()))
+		assert.Equal(t, "", settings.FilePath)
+	})
+}
@@ -0,0 +1,219 @@
+package process_settings
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// A Subscription is a handle to a registered callback, which can be used to cancel it.
+type Subscription struct {
+	cancel func()
+	once   sync.Once
+}
+
+// Cancel removes the callback, so it is not called again.
+// Cancelling a Subscription more than once does nothing.
+func (s *Subscription) Cancel() {
+	s.once.Do(s.cancel)
+}
+
+// A callbackRegistry holds callbacks in the order they were registered.
+// It is copy-on-write: registering or cancelling a callback replaces the slice of entries,
+// so the callbacks can be called without holding the lock, and a callback may register or
+// cancel callbacks itself.
+type callbackRegistry[T any] struct {
+	mu      sync.Mutex
+	nextID  uint64
+	entries []registryEntry[T]
+}
+
+type registryEntry[T any] struct {
+	id       uint64
+	callback T
+	running  *int32 // Set to 1 while the callback runs on its own goroutine; see runCallback
+}
+
+// register adds a callback and returns a Subscription that removes it again.
+func (r *callbackRegistry[T]) register(callback T) *Subscription {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	id := r.nextID
+	entries := make([]registryEntry[T], len(r.entries), len(r.entries)+1)
+	copy(entries, r.entries)
+	r.entries = append(entries, registryEntry[T]{id, callback, new(int32)})
+
+	return &Subscription{cancel: func() { r.remove(id) }}
+}
+
+func (r *callbackRegistry[T]) remove(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]registryEntry[T], 0, len(r.entries))
+	for _, entry := range r.entries {
+		if entry.id != id {
+			entries = append(entries, entry)
+		}
+	}
+	r.entries = entries
+}
+
+// snapshot returns the registered entries. The returned slice is never modified.
+func (r *callbackRegistry[T]) snapshot() []registryEntry[T] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.entries
+}
+
+// len returns the number of registered callbacks.
+func (r *callbackRegistry[T]) len() int {
+	return len(r.snapshot())
+}
+
+// A changeListener is a callback registered with OnChange.
+type changeListener struct {
+	settingPath []string
+	fn          func(old, new interface{})
+}
+
+// OnChange registers a function to be called after a reload that changed the effective
+// value of the setting at the given path, under the static context of this process.
+// The function is called with the old and new values, where a setting that did not exist
+// is passed as nil. It is not called when the setting did not change, even if other
+// settings did. An empty path listens to all the effective settings, and the function is
+// then called with the old and new effective settings after any reload that changed them.
+// The returned Subscription cancels the function.
+func (ps *ProcessSettings) OnChange(settingPath []string, fn func(old, new interface{})) *Subscription {
+	return ps.changeListeners.register(changeListener{settingPath, fn})
+}
+
+func (ps *ProcessSettings) notifyChangeListeners(previous, current *settingsSnapshot) {
+	for _, entry := range ps.changeListeners.snapshot() {
+		oldValue, oldErr := listenedValue(previous, entry.callback.settingPath)
+		newValue, newErr := listenedValue(current, entry.callback.settingPath)
+		if (oldErr == nil) != (newErr == nil) || !reflect.DeepEqual(oldValue, newValue) {
+			fn := entry.callback.fn
+			name := fmt.Sprintf("OnChange callback for '%s'", dotDelimitedSettingsPath(entry.callback.settingPath))
+			ps.runCallback(name, entry.running, func() { fn(oldValue, newValue) })
+		}
+	}
+}
+
+// listenedValue returns the value a change listener compares: the effective value of the
+// setting at the path, or all the effective settings when the path is empty.
+func listenedValue(snapshot *settingsSnapshot, settingPath []string) (interface{}, error) {
+	if len(settingPath) == 0 {
+		return snapshot.effective, nil
+	}
+	return snapshot.get(settingPath)
+}
+
+// An UpdateEvent is delivered by Subscribe after a reload that changed the settings.
+type UpdateEvent struct {
+	PreviousVersion int          // The meta.version of the settings before the update
+	Version         int          // The meta.version of the settings after the update
+	Diff            SettingsDiff // The difference in effective settings for this process's static context
+
+	previous, current *settingsSnapshot
+}
+
+func newUpdateEvent(previous, current *settingsSnapshot) UpdateEvent {
+	return UpdateEvent{
+		PreviousVersion: previous.version,
+		Version:         current.version,
+		Diff:            current.diff,
+		previous:        previous,
+		current:         current,
+	}
+}
+
+// coalesce returns a single event covering both an event that was never received and the
+// event that followed it.
+func coalesce(pending, next UpdateEvent) UpdateEvent {
+	event := newUpdateEvent(pending.previous, next.current)
+	event.Diff = Diff(pending.previous.effective, next.current.effective)
+	return event
+}
+
+// An updateSubscriber is the sending side of a channel returned by Subscribe.
+type updateSubscriber struct {
+	mu     sync.Mutex
+	events chan UpdateEvent
+	closed bool
+}
+
+// deliver sends the event without ever blocking. If the previous event is still waiting to be
+// received, it is replaced by one that covers both.
+func (s *updateSubscriber) deliver(event UpdateEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		select {
+		case pending := <-s.events:
+			event = coalesce(pending, event)
+		default:
+			// The pending event was received in the meantime.
+		}
+		// Only deliver sends on the channel, and it holds the lock, so there is room now.
+		s.events <- event
+	}
+}
+
+func (s *updateSubscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
+
+// Subscribe returns a channel that receives an UpdateEvent after every reload that changed
+// the settings, so consumers can react in their own goroutines instead of holding up the
+// monitor the way a slow WhenUpdated callback does.
+//
+// Sending on the channel never blocks. The channel holds a single pending event: when a
+// consumer falls behind, the pending event and the new one are coalesced into one event
+// spanning both, with the PreviousVersion of the older event, the Version of the newer
+// one, and the Diff between them. Consumers may therefore skip intermediate versions, but
+// always end up seeing the latest one.
+//
+// The channel is closed when ctx is cancelled or the ProcessSettings is closed.
+func (ps *ProcessSettings) Subscribe(ctx context.Context) <-chan UpdateEvent {
+	subscriber := &updateSubscriber{events: make(chan UpdateEvent, 1)}
+	if ps.isClosed() {
+		subscriber.close()
+		return subscriber.events
+	}
+
+	subscription := ps.updateSubscribers.register(subscriber)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-ps.closing:
+		}
+		subscription.Cancel()
+		subscriber.close()
+	}()
+	return subscriber.events
+}
+
+func (ps *ProcessSettings) notifySubscribers(previous, current *settingsSnapshot) {
+	event := newUpdateEvent(previous, current)
+	for _, entry := range ps.updateSubscribers.snapshot() {
+		entry.callback.deliver(event)
+	}
+}
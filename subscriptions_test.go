@@ -0,0 +1,258 @@
+package process_settings
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSettings_OnChange(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeHoneypotSettingsFile(t, filePath, 1, "sip", 100)
+	settings, err := NewProcessSettingsFromFile(filePath, map[string]interface{}{"app": "telecom"})
+	assert.Nil(t, err)
+
+	type change struct{ old, new interface{} }
+	var logStreamChanges, answerOddsChanges, honeypotChanges []change
+	logStreamSubscription := settings.OnChange([]string{"honeypot", "log_stream"}, func(old, new interface{}) {
+		logStreamChanges = append(logStreamChanges, change{old, new})
+	})
+	settings.OnChange([]string{"honeypot", "answer_odds"}, func(old, new interface{}) {
+		answerOddsChanges = append(answerOddsChanges, change{old, new})
+	})
+	settings.OnChange([]string{"honeypot"}, func(old, new interface{}) {
+		honeypotChanges = append(honeypotChanges, change{old, new})
+	})
+
+	t.Run("Is not called when registered", func(t *testing.T) {
+		assert.Empty(t, logStreamChanges)
+	})
+
+	t.Run("Is called with the old and new values when the setting changes", func(t *testing.T) {
+		writeHoneypotSettingsFile(t, filePath, 2, "http", 100)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, []change{{"sip", "http"}}, logStreamChanges)
+		assert.Empty(t, answerOddsChanges)
+		assert.Equal(t, []change{{
+			map[string]interface{}{"log_stream": "sip", "answer_odds": 100},
+			map[string]interface{}{"log_stream": "http", "answer_odds": 100},
+		}}, honeypotChanges)
+	})
+
+	t.Run("Is not called when other settings change", func(t *testing.T) {
+		writeHoneypotSettingsFile(t, filePath, 3, "http", 50)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Len(t, logStreamChanges, 1)
+		assert.Equal(t, []change{{100, 50}}, answerOddsChanges)
+	})
+
+	t.Run("Is called with nil when the setting is removed", func(t *testing.T) {
+		writeHoneypotSettingsFile(t, filePath, 4, "", 50)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, change{"http", nil}, logStreamChanges[len(logStreamChanges)-1])
+	})
+
+	t.Run("Is not called after it has been cancelled", func(t *testing.T) {
+		logStreamSubscription.Cancel()
+		logStreamSubscription.Cancel()
+
+		writeHoneypotSettingsFile(t, filePath, 5, "sip", 50)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Len(t, logStreamChanges, 2)
+		assert.Equal(t, 2, settings.changeListeners.len())
+	})
+
+	t.Run("The global instance registers the function", func(t *testing.T) {
+		SetGlobalProcessSettings(settings)
+		defer SetGlobalProcessSettings(nil)
+
+		var changes []change
+		subscription, err := OnChange([]string{"honeypot", "answer_odds"}, func(old, new interface{}) {
+			changes = append(changes, change{old, new})
+		})
+		assert.Nil(t, err)
+		defer subscription.Cancel()
+
+		writeHoneypotSettingsFile(t, filePath, 6, "sip", 75)
+		_, err = settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, []change{{50, 75}}, changes)
+	})
+}
+
+func TestProcessSettings_OnChangeWithEmptyPath(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeHoneypotSettingsFile(t, filePath, 1, "sip", 100)
+	settings, err := NewProcessSettingsFromFile(filePath, map[string]interface{}{"app": "telecom"})
+	assert.Nil(t, err)
+
+	var nilPathChanges, emptyPathChanges int
+	settings.OnChange(nil, func(old, new interface{}) {
+		nilPathChanges++
+		assert.Equal(t, map[string]interface{}{"honeypot": map[string]interface{}{"log_stream": "sip", "answer_odds": 100}}, old)
+		assert.Equal(t, map[string]interface{}{"honeypot": map[string]interface{}{"log_stream": "http", "answer_odds": 100}}, new)
+	})
+	settings.OnChange([]string{}, func(old, new interface{}) { emptyPathChanges++ })
+
+	t.Run("Is called with all the effective settings when any of them changed", func(t *testing.T) {
+		writeHoneypotSettingsFile(t, filePath, 2, "http", 100)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, 1, nilPathChanges)
+		assert.Equal(t, 1, emptyPathChanges)
+	})
+
+	t.Run("Is not called when the effective settings did not change", func(t *testing.T) {
+		writeHoneypotSettingsFile(t, filePath, 3, "http", 100)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Equal(t, 1, nilPathChanges)
+		assert.Equal(t, 1, emptyPathChanges)
+	})
+}
+
+func TestProcessSettings_Subscribe(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeHoneypotSettingsFile(t, filePath, 1, "sip", 100)
+	settings, err := NewProcessSettingsFromFile(filePath, map[string]interface{}{"app": "telecom"})
+	assert.Nil(t, err)
+	defer settings.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := settings.Subscribe(ctx)
+
+	t.Run("Delivers the versions and the diff of an update", func(t *testing.T) {
+		writeHoneypotSettingsFile(t, filePath, 2, "http", 100)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		event := receiveUpdateEvent(t, events)
+		assert.Equal(t, 1, event.PreviousVersion)
+		assert.Equal(t, 2, event.Version)
+		assert.Equal(t, []SettingChange{{"honeypot.log_stream", "sip", "http"}}, event.Diff.Changed)
+	})
+
+	t.Run("Does not deliver reloads that did not change the settings", func(t *testing.T) {
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+
+		assert.Len(t, events, 0)
+	})
+
+	t.Run("Coalesces updates that were not received into one", func(t *testing.T) {
+		writeHoneypotSettingsFile(t, filePath, 3, "http", 50)
+		_, err := settings.Reload()
+		assert.Nil(t, err)
+		writeHoneypotSettingsFile(t, filePath, 4, "sip", 50)
+		_, err = settings.Reload()
+		assert.Nil(t, err)
+
+		event := receiveUpdateEvent(t, events)
+		assert.Equal(t, 2, event.PreviousVersion)
+		assert.Equal(t, 4, event.Version)
+		assert.Equal(t, SettingsDiff{Changed: []SettingChange{
+			{"honeypot.answer_odds", 100, 50},
+			{"honeypot.log_stream", "http", "sip"},
+		}}, event.Diff)
+		assert.Len(t, events, 0)
+	})
+
+	t.Run("Closes the channel when the context is cancelled", func(t *testing.T) {
+		cancel()
+		assertUpdateEventsClosed(t, events)
+		assert.Eventually(t, func() bool { return settings.updateSubscribers.len() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Closes the channel when the settings are closed", func(t *testing.T) {
+		events := settings.Subscribe(context.Background())
+		assert.Nil(t, settings.Close())
+		assertUpdateEventsClosed(t, events)
+	})
+
+	t.Run("Returns a closed channel once the settings are closed", func(t *testing.T) {
+		assertUpdateEventsClosed(t, settings.Subscribe(context.Background()))
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "combined_process_settings.yml")
+	writeHoneypotSettingsFile(t, filePath, 1, "sip", 100)
+	settings, err := NewProcessSettingsFromFile(filePath, map[string]interface{}{"app": "telecom"})
+	assert.Nil(t, err)
+	defer settings.Close()
+
+	SetGlobalProcessSettings(settings)
+	defer SetGlobalProcessSettings(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := Subscribe(ctx)
+	assert.Nil(t, err)
+
+	writeHoneypotSettingsFile(t, filePath, 2, "sip", 75)
+	_, err = settings.Reload()
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, receiveUpdateEvent(t, events).Version)
+}
+
+func receiveUpdateEvent(t *testing.T, events <-chan UpdateEvent) UpdateEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		assert.True(t, ok, "the channel was closed")
+		return event
+	case <-time.After(time.Second):
+		assert.Fail(t, "no update event was delivered")
+		return UpdateEvent{}
+	}
+}
+
+func assertUpdateEventsClosed(t *testing.T, events <-chan UpdateEvent) {
+	t.Helper()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok, "an update event was delivered instead of closing the channel")
+	case <-time.After(time.Second):
+		assert.Fail(t, "the channel was not closed")
+	}
+}
+
+// writeHoneypotSettingsFile writes a combined settings file with honeypot settings targeted at telecom.
+// An empty log stream leaves the setting out.
+func writeHoneypotSettingsFile(t *testing.T, filePath string, version int, logStream string, answerOdds int) {
+	t.Helper()
+	logStreamSetting := ""
+	if logStream != "" {
+		logStreamSetting = "\n      log_stream: " + logStream
+	}
+	contents := fmt.Sprintf(`---
+- filename: honeypot.yml
+  target:
+    app: telecom
+  settings:
+    honeypot:
+      answer_odds: %d%s
+- meta:
+    version: %d
+    END: true
+`, answerOdds, logStreamSetting, version)
+	assert.Nil(t, os.WriteFile(filePath, []byte(contents), 0o644))
+}
@@ -58,3 +58,28 @@ func (t *TargetEvaluator) isTargetMatch(settingsFile SettingsFile) bool {
 
 	return mapContains(settingsFile.Target, t.targetingContext)
 }
+
+// matchingSettingsFiles returns the settings files whose target matches the targeting context, in their original order.
+func (t *TargetEvaluator) matchingSettingsFiles(settingsFiles []SettingsFile) []SettingsFile {
+	var matching []SettingsFile
+	for _, settingsFile := range settingsFiles {
+		if t.isTargetMatch(settingsFile) {
+			matching = append(matching, settingsFile)
+		}
+	}
+	return matching
+}
+
+// withDynamicContext returns a new TargetEvaluator whose targeting context is the
+// targeting context of t with the dynamic context merged over it.
+// t itself is left unchanged.
+func (t *TargetEvaluator) withDynamicContext(dynamicContext map[string]interface{}) TargetEvaluator {
+	targetingContext := make(map[string]interface{}, len(t.targetingContext)+len(dynamicContext))
+	for key, value := range t.targetingContext {
+		targetingContext[key] = value
+	}
+	for key, value := range dynamicContext {
+		targetingContext[key] = value
+	}
+	return TargetEvaluator{targetingContext}
+}
@@ -192,3 +192,25 @@ func TestTargetEvaluation(t *testing.T) {
 		})
 	}
 }
+
+func TestTargetEvaluatorWithDynamicContext(t *testing.T) {
+	evaluator := TargetEvaluator{map[string]interface{}{
+		"app":    "telecom",
+		"region": "west",
+	}}
+
+	merged := evaluator.withDynamicContext(map[string]interface{}{
+		"region":    "east",
+		"caller_id": "+18053334444",
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"app":       "telecom",
+		"region":    "east",
+		"caller_id": "+18053334444",
+	}, merged.targetingContext)
+	assert.Equal(t, map[string]interface{}{
+		"app":    "telecom",
+		"region": "west",
+	}, evaluator.targetingContext)
+}
@@ -0,0 +1,240 @@
+package process_settings
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// A SettingConversionError is returned by the typed getters and Decode when the
+// value of a setting cannot be converted to the requested type.
+type SettingConversionError struct {
+	SettingPath []string
+	Value       interface{}
+	TargetType  string
+	Err         error // The underlying decoding error, if any
+}
+
+func (e *SettingConversionError) Error() string {
+	message := fmt.Sprintf("The setting '%s' with value %v (%T) cannot be converted to %s", dotDelimitedSettingsPath(e.SettingPath), e.Value, e.Value, e.TargetType)
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *SettingConversionError) Unwrap() error {
+	return e.Err
+}
+
+// GetString returns the value of a setting as a string.
+// Numbers and booleans are formatted as strings.
+func (ps *ProcessSettings) GetString(settingPath ...string) (string, error) {
+	value, err := ps.Get(settingPath...)
+	if err != nil {
+		return "", err
+	}
+	if converted, ok := toString(value); ok {
+		return converted, nil
+	}
+	return "", &SettingConversionError{settingPath, value, "string", nil}
+}
+
+// GetInt returns the value of a setting as an int.
+// Floats without a fractional part and numeric strings are converted.
+func (ps *ProcessSettings) GetInt(settingPath ...string) (int, error) {
+	value, err := ps.Get(settingPath...)
+	if err != nil {
+		return 0, err
+	}
+	if converted, ok := toInt64(value); ok && converted >= math.MinInt && converted <= math.MaxInt {
+		return int(converted), nil
+	}
+	return 0, &SettingConversionError{settingPath, value, "int", nil}
+}
+
+// GetInt64 returns the value of a setting as an int64.
+// Floats without a fractional part and numeric strings are converted.
+func (ps *ProcessSettings) GetInt64(settingPath ...string) (int64, error) {
+	value, err := ps.Get(settingPath...)
+	if err != nil {
+		return 0, err
+	}
+	if converted, ok := toInt64(value); ok {
+		return converted, nil
+	}
+	return 0, &SettingConversionError{settingPath, value, "int64", nil}
+}
+
+// GetFloat64 returns the value of a setting as a float64.
+// Integers and numeric strings are converted.
+func (ps *ProcessSettings) GetFloat64(settingPath ...string) (float64, error) {
+	value, err := ps.Get(settingPath...)
+	if err != nil {
+		return 0, err
+	}
+	if converted, ok := toFloat64(value); ok {
+		return converted, nil
+	}
+	return 0, &SettingConversionError{settingPath, value, "float64", nil}
+}
+
+// GetBool returns the value of a setting as a bool.
+// Strings accepted by strconv.ParseBool are converted.
+func (ps *ProcessSettings) GetBool(settingPath ...string) (bool, error) {
+	value, err := ps.Get(settingPath...)
+	if err != nil {
+		return false, err
+	}
+	if converted, ok := toBool(value); ok {
+		return converted, nil
+	}
+	return false, &SettingConversionError{settingPath, value, "bool", nil}
+}
+
+// GetDuration returns the value of a setting as a time.Duration.
+// Strings are parsed with time.ParseDuration (e.g. "1m30s"), and numbers are
+// interpreted as a number of seconds, which must fit in a time.Duration.
+func (ps *ProcessSettings) GetDuration(settingPath ...string) (time.Duration, error) {
+	value, err := ps.Get(settingPath...)
+	if err != nil {
+		return 0, err
+	}
+	if converted, ok := toDuration(value); ok {
+		return converted, nil
+	}
+	return 0, &SettingConversionError{settingPath, value, "time.Duration", nil}
+}
+
+// GetStringSlice returns the value of a setting as a []string.
+// Every element of the list must be convertible by GetString.
+func (ps *ProcessSettings) GetStringSlice(settingPath ...string) ([]string, error) {
+	value, err := ps.Get(settingPath...)
+	if err != nil {
+		return nil, err
+	}
+	if converted, ok := toStringSlice(value); ok {
+		return converted, nil
+	}
+	return nil, &SettingConversionError{settingPath, value, "[]string", nil}
+}
+
+// GetStringMap returns the value of a setting as a map[string]interface{}.
+func (ps *ProcessSettings) GetStringMap(settingPath ...string) (map[string]interface{}, error) {
+	value, err := ps.Get(settingPath...)
+	if err != nil {
+		return nil, err
+	}
+	if converted, ok := value.(map[string]interface{}); ok {
+		return converted, nil
+	}
+	return nil, &SettingConversionError{settingPath, value, "map[string]interface{}", nil}
+}
+
+func toString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float64:
+		return int64(v), v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64
+	case string:
+		converted, err := strconv.ParseInt(v, 10, 64)
+		return converted, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case string:
+		converted, err := strconv.ParseFloat(v, 64)
+		return converted, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		converted, err := strconv.ParseBool(v)
+		return converted, err == nil
+	default:
+		return false, false
+	}
+}
+
+func toDuration(value interface{}) (time.Duration, bool) {
+	switch v := value.(type) {
+	case string:
+		converted, err := time.ParseDuration(v)
+		return converted, err == nil
+	case float64:
+		// Written so that NaN is out of range too; float64(math.MaxInt64) rounds up to 2^63.
+		nanoseconds := v * float64(time.Second)
+		if !(nanoseconds >= math.MinInt64 && nanoseconds < math.MaxInt64) {
+			return 0, false
+		}
+		return time.Duration(nanoseconds), true
+	default:
+		seconds, ok := toInt64(value)
+		if !ok || seconds > math.MaxInt64/int64(time.Second) || seconds < math.MinInt64/int64(time.Second) {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+}
+
+func toStringSlice(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		converted := make([]string, len(v))
+		for i, element := range v {
+			str, ok := toString(element)
+			if !ok {
+				return nil, false
+			}
+			converted[i] = str
+		}
+		return converted, true
+	default:
+		return nil, false
+	}
+}
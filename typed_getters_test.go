@@ -0,0 +1,218 @@
+package process_settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var typedSettings = newProcessSettings(nil, []SettingsFile{
+	{
+		FileName: "typed.yml",
+		Settings: map[string]interface{}{
+			"string":         "sip",
+			"int":            100,
+			"int64":          int64(9007199254740993),
+			"float":          0.25,
+			"huge_float":     1e12,
+			"whole_float":    3.0,
+			"numeric_string": "42",
+			"bool":           true,
+			"bool_string":    "false",
+			"duration":       "1m30s",
+			"strings":        []interface{}{"a", "b", 3},
+			"mixed":          []interface{}{"a", map[string]interface{}{}},
+			"map":            map[string]interface{}{"answer_odds": 100},
+			"nil":            nil,
+		},
+	},
+}, nil)
+
+func TestProcessSettings_TypedGetters(t *testing.T) {
+	tests := []struct {
+		name          string
+		get           func(settingPath ...string) (interface{}, error)
+		settingPath   []string
+		expectedValue interface{}
+		expectedError string
+	}{
+		{
+			name:          "GetString returns a string",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetString(p...) },
+			settingPath:   []string{"string"},
+			expectedValue: "sip",
+		},
+		{
+			name:          "GetString formats a number",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetString(p...) },
+			settingPath:   []string{"float"},
+			expectedValue: "0.25",
+		},
+		{
+			name:          "GetString returns an error for a map",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetString(p...) },
+			settingPath:   []string{"map"},
+			expectedError: "The setting 'map' with value map[answer_odds:100] (map[string]interface {}) cannot be converted to string",
+		},
+		{
+			name:          "GetString returns an error for nil",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetString(p...) },
+			settingPath:   []string{"nil"},
+			expectedError: "The setting 'nil' with value <nil> (<nil>) cannot be converted to string",
+		},
+		{
+			name:          "GetString returns not found errors unchanged",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetString(p...) },
+			settingPath:   []string{"honeypot", "log_stream"},
+			expectedError: "The setting 'honeypot.log_stream' was not found",
+		},
+		{
+			name:          "GetInt returns an int",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetInt(p...) },
+			settingPath:   []string{"int"},
+			expectedValue: 100,
+		},
+		{
+			name:          "GetInt converts a whole float",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetInt(p...) },
+			settingPath:   []string{"whole_float"},
+			expectedValue: 3,
+		},
+		{
+			name:          "GetInt converts a numeric string",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetInt(p...) },
+			settingPath:   []string{"numeric_string"},
+			expectedValue: 42,
+		},
+		{
+			name:          "GetInt returns an error for a fractional float",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetInt(p...) },
+			settingPath:   []string{"float"},
+			expectedError: "The setting 'float' with value 0.25 (float64) cannot be converted to int",
+		},
+		{
+			name:          "GetInt64 returns an int64",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetInt64(p...) },
+			settingPath:   []string{"int64"},
+			expectedValue: int64(9007199254740993),
+		},
+		{
+			name:          "GetInt64 converts an int",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetInt64(p...) },
+			settingPath:   []string{"int"},
+			expectedValue: int64(100),
+		},
+		{
+			name:          "GetFloat64 returns a float",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetFloat64(p...) },
+			settingPath:   []string{"float"},
+			expectedValue: 0.25,
+		},
+		{
+			name:          "GetFloat64 converts an int",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetFloat64(p...) },
+			settingPath:   []string{"int"},
+			expectedValue: 100.0,
+		},
+		{
+			name:          "GetFloat64 returns an error for a non numeric string",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetFloat64(p...) },
+			settingPath:   []string{"string"},
+			expectedError: "The setting 'string' with value sip (string) cannot be converted to float64",
+		},
+		{
+			name:          "GetBool returns a bool",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetBool(p...) },
+			settingPath:   []string{"bool"},
+			expectedValue: true,
+		},
+		{
+			name:          "GetBool converts a boolean string",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetBool(p...) },
+			settingPath:   []string{"bool_string"},
+			expectedValue: false,
+		},
+		{
+			name:          "GetBool returns an error for an int",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetBool(p...) },
+			settingPath:   []string{"int"},
+			expectedError: "The setting 'int' with value 100 (int) cannot be converted to bool",
+		},
+		{
+			name:          "GetDuration parses a duration string",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetDuration(p...) },
+			settingPath:   []string{"duration"},
+			expectedValue: 90 * time.Second,
+		},
+		{
+			name:          "GetDuration interprets numbers as seconds",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetDuration(p...) },
+			settingPath:   []string{"float"},
+			expectedValue: 250 * time.Millisecond,
+		},
+		{
+			name:          "GetDuration returns an error for an invalid string",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetDuration(p...) },
+			settingPath:   []string{"string"},
+			expectedError: "The setting 'string' with value sip (string) cannot be converted to time.Duration",
+		},
+		{
+			name:          "GetDuration returns an error for a float number of seconds out of range",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetDuration(p...) },
+			settingPath:   []string{"huge_float"},
+			expectedError: "The setting 'huge_float' with value 1e+12 (float64) cannot be converted to time.Duration",
+		},
+		{
+			name:          "GetDuration returns an error for an integer number of seconds out of range",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetDuration(p...) },
+			settingPath:   []string{"int64"},
+			expectedError: "The setting 'int64' with value 9007199254740993 (int64) cannot be converted to time.Duration",
+		},
+		{
+			name:          "GetStringSlice converts each element",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetStringSlice(p...) },
+			settingPath:   []string{"strings"},
+			expectedValue: []string{"a", "b", "3"},
+		},
+		{
+			name:          "GetStringSlice returns an error when an element cannot be converted",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetStringSlice(p...) },
+			settingPath:   []string{"mixed"},
+			expectedError: "The setting 'mixed' with value [a map[]] ([]interface {}) cannot be converted to []string",
+		},
+		{
+			name:          "GetStringMap returns a map",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetStringMap(p...) },
+			settingPath:   []string{"map"},
+			expectedValue: map[string]interface{}{"answer_odds": 100},
+		},
+		{
+			name:          "GetStringMap returns an error for a list",
+			get:           func(p ...string) (interface{}, error) { return typedSettings.GetStringMap(p...) },
+			settingPath:   []string{"strings"},
+			expectedError: "The setting 'strings' with value [a b 3] ([]interface {}) cannot be converted to map[string]interface{}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := test.get(test.settingPath...)
+			if test.expectedError == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, test.expectedValue, value)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedError, err.Error())
+			}
+		})
+	}
+
+	t.Run("Conversion errors can be inspected with errors.As", func(t *testing.T) {
+		_, err := typedSettings.GetInt("string")
+		var conversionError *SettingConversionError
+		assert.ErrorAs(t, err, &conversionError)
+		assert.Equal(t, []string{"string"}, conversionError.SettingPath)
+		assert.Equal(t, "int", conversionError.TargetType)
+	})
+}